@@ -13,11 +13,17 @@ import (
 	"time"
 
 	"api-gateway/middleware"
+	"api-gateway/router"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -25,10 +31,37 @@ var (
 	goAPIServiceURL  = getEnv("GO_API_SERVICE_URL", "http://go-api:8080")
 	pythonServiceURL = getEnv("PYTHON_SERVICE_URL", "http://python-processor:8081")
 	redisAddr        = getEnv("REDIS_ADDR", "redis:6379")
-	jwtSecret        = getEnv("JWT_SECRET", "your-secret-key-change-in-production")
+	authJWKSURL      = getEnv("AUTH_JWKS_URL", authServiceURL+"/.well-known/jwks.json")
+	tokenIdleTimeout = getEnvDuration("TOKEN_IDLE_TIMEOUT", 30*time.Minute)
+
+	// Backend discovery: "static" reads a comma-separated *_SERVICE_URLS env
+	// var once, "dns" resolves a SRV record per service name, and "consul"
+	// polls a Consul catalog. All three feed the same ServiceRegistry.
+	serviceDiscovery    = getEnv("SERVICE_DISCOVERY", "static")
+	consulAddr          = getEnv("CONSUL_ADDR", "http://consul:8500")
+	discoveryInterval   = getEnvDuration("SERVICE_DISCOVERY_INTERVAL", 15*time.Second)
+	healthCheckInterval = getEnvDuration("HEALTH_CHECK_INTERVAL", 10*time.Second)
 )
 
 func main() {
+	ctx := context.Background()
+
+	// Tracing: one TracerProvider per service, exporting to an OTLP
+	// collector, so a request's trace follows it from here through the
+	// proxied auth-service/go-api/python-processor call.
+	shutdownTracer, err := initTracer(ctx, "api-gateway")
+	if err != nil {
+		log.Printf("Warning: tracing disabled: %v", err)
+	} else {
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracer(shutdownCtx); err != nil {
+				log.Printf("Warning: tracer shutdown failed: %v", err)
+			}
+		}()
+	}
+
 	// Initialize Redis client
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: redisAddr,
@@ -36,7 +69,6 @@ func main() {
 	})
 
 	// Test Redis connection
-	ctx := context.Background()
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		log.Printf("Warning: Redis connection failed: %v", err)
 	}
@@ -49,6 +81,7 @@ func main() {
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.REDMetrics)
 
 	// CORS middleware
 	allowedOrigins := os.Getenv("CORS_ALLOWED_ORIGINS")
@@ -77,44 +110,87 @@ func main() {
 		w.Write([]byte(`{"status":"healthy","service":"api-gateway"}`))
 	})
 
-	// Rate limiter middleware
-	rateLimiter := middleware.NewRateLimiter(redisClient, 100, time.Minute)
+	// Circuit breaker state/trip metrics
+	r.Handle("/metrics", promhttp.Handler())
+
+	// Rate limiter middleware: a global per-IP allowance, plus a dedicated
+	// stricter per-IP+email policy on the login/register routes.
+	rateLimiter := middleware.NewRateLimiter(redisClient, middleware.Rule{
+		Count:  100,
+		Window: time.Minute,
+		Scope:  middleware.ScopeIP,
+	})
+	loginAttemptLimit := rateLimiter.LoginAttemptLimit(middleware.Rule{
+		Count:  5,
+		Window: 30 * time.Minute,
+	})
+	// Refresh is reachable without a password, so it gets its own per-route
+	// cap on top of the global one rather than the login/register IP+email
+	// policy.
+	rateLimiter.For("/api/v1/auth/refresh", middleware.Rule{Count: 30, Window: time.Minute, Scope: middleware.ScopeRoute})
+
+	// Service registry: tracks a pool of backend instances per logical
+	// service, health-checks them in the background, and picks one per
+	// request with a weighted round-robin strategy. Backends come from a
+	// static *_SERVICE_URLS env var by default, or are kept in sync with a
+	// DNS SRV record or Consul catalog when SERVICE_DISCOVERY asks for it.
+	registry := router.NewServiceRegistry(healthCheckInterval, 3, 2)
+	defer registry.Stop()
 
-	// Circuit breaker for each service
-	authCB := middleware.NewCircuitBreaker("auth-service")
-	goAPICB := middleware.NewCircuitBreaker("go-api")
-	pythonCB := middleware.NewCircuitBreaker("python-processor")
+	registerService(registry, "auth-service", "AUTH_SERVICE_URLS", authServiceURL)
+	registerService(registry, "go-api", "GO_API_SERVICE_URLS", goAPIServiceURL)
+	registerService(registry, "python-processor", "PYTHON_SERVICE_URLS", pythonServiceURL)
+
+	// Circuit breaker per backend instance, so one failing instance tripping
+	// its breaker doesn't take the whole service down with it.
+	cbPool := middleware.NewCircuitBreakerPool()
 
 	// JWT middleware
-	jwtMiddleware := middleware.NewJWTMiddleware(jwtSecret)
+	jwtMiddleware := middleware.NewJWTMiddleware(authJWKSURL, redisClient, tokenIdleTimeout)
 
 	// Auth service routes (no auth required for login/register)
 	r.Route("/api/v1/auth", func(r chi.Router) {
 		r.Use(rateLimiter.RateLimit)
-		r.Use(authCB.Middleware)
-		r.HandleFunc("/*", createProxy(authServiceURL))
+
+		// Login and register get a dedicated, stricter per-IP+email policy
+		// on top of the global one, to blunt credential stuffing.
+		r.With(loginAttemptLimit).Post("/login", createProxy("auth-service", registry, cbPool))
+		r.With(loginAttemptLimit).Post("/register", createProxy("auth-service", registry, cbPool))
+
+		// Refresh is reachable without a password, so it's broken out of the
+		// wildcard below to get its own per-route allowance via For, on top
+		// of the global one.
+		r.Post("/refresh", createProxy("auth-service", registry, cbPool))
+
+		r.HandleFunc("/*", createProxy("auth-service", registry, cbPool))
 	})
 
 	// Go API routes (protected)
 	r.Route("/api/v1/data", func(r chi.Router) {
 		r.Use(rateLimiter.RateLimit)
 		r.Use(jwtMiddleware.Authenticate)
-		r.Use(goAPICB.Middleware)
-		r.HandleFunc("/*", createProxy(goAPIServiceURL))
+		r.HandleFunc("/*", createProxy("go-api", registry, cbPool))
+	})
+
+	// Replication policy/job management routes (protected); go-api applies
+	// its own per-route RBAC check, the same as it does for /housing/*.
+	r.Route("/api/v1/replication", func(r chi.Router) {
+		r.Use(rateLimiter.RateLimit)
+		r.Use(jwtMiddleware.Authenticate)
+		r.HandleFunc("/*", createProxy("go-api", registry, cbPool))
 	})
 
 	// Python processor routes
 	r.Route("/api/v1/process", func(r chi.Router) {
 		r.Use(rateLimiter.RateLimit)
-		r.Use(pythonCB.Middleware)
-		r.HandleFunc("/*", createProxy(pythonServiceURL))
+		r.HandleFunc("/*", createProxy("python-processor", registry, cbPool))
 	})
 
 	// Start server
 	port := getEnv("PORT", "8000")
 	srv := &http.Server{
 		Addr:         ":" + port,
-		Handler:      r,
+		Handler:      otelhttp.NewHandler(r, "api-gateway"),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -145,30 +221,93 @@ func main() {
 	log.Println("Server exited")
 }
 
-func createProxy(targetURL string) http.HandlerFunc {
-	target, err := url.Parse(targetURL)
-	if err != nil {
-		log.Fatalf("Failed to parse target URL %s: %v", targetURL, err)
+// registerService registers serviceName's backend pool with registry, using
+// a weighted round-robin strategy, and sets up live discovery for it when
+// SERVICE_DISCOVERY asks for something other than the static env var list.
+func registerService(registry *router.ServiceRegistry, serviceName, urlsEnv, defaultURL string) {
+	urls := strings.Split(getEnv(urlsEnv, defaultURL), ",")
+	for i := range urls {
+		urls[i] = strings.TrimSpace(urls[i])
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
-	
-	// Custom director to preserve original request path
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
-		req.Host = target.Host
-		req.URL.Scheme = target.Scheme
-		req.URL.Host = target.Host
+	registry.RegisterService(serviceName, urls...)
+	registry.SetStrategy(serviceName, &router.WeightedRoundRobin{})
+
+	switch serviceDiscovery {
+	case "dns":
+		registry.Watch(serviceName, &router.DNSSRVResolver{Service: serviceName, Proto: "tcp", Name: serviceName}, discoveryInterval)
+	case "consul":
+		registry.Watch(serviceName, router.NewConsulResolver(consulAddr, serviceName), discoveryInterval)
 	}
+}
+
+// proxyTransport wraps the default transport so the traceparent header for
+// the span started below is injected into the outgoing request, letting
+// auth-service/go-api/python-processor continue the same trace.
+var proxyTransport = otelhttp.NewTransport(http.DefaultTransport)
+
+// createProxy picks a healthy backend instance of serviceName on every
+// request via registry, proxies to it through that instance's own circuit
+// breaker, and feeds the observed outcome back into the registry's health
+// tracking alongside the periodic active health checks.
+func createProxy(serviceName string, registry *router.ServiceRegistry, cbPool *middleware.CircuitBreakerPool) http.HandlerFunc {
+	tracer := otel.Tracer("api-gateway")
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), "main.createProxy", trace.WithAttributes(
+			attribute.String("upstream.service", serviceName),
+		))
+		defer span.End()
+		r = r.WithContext(ctx)
+
 		// Explicitly handle OPTIONS to prevent 405 from backends that don't implementation it
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		proxy.ServeHTTP(w, r)
+
+		endpoint, err := registry.GetEndpoint(serviceName)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"service unavailable","message":"no healthy upstream available"}`))
+			return
+		}
+
+		target, err := url.Parse(endpoint)
+		if err != nil {
+			log.Printf("Failed to parse upstream URL %s for %s: %v", endpoint, serviceName, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = proxyTransport
+
+		// Custom director to preserve original request path
+		originalDirector := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			originalDirector(req)
+			req.Host = target.Host
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+		}
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			registry.ReportResult(serviceName, endpoint, resp.StatusCode < http.StatusInternalServerError)
+			return nil
+		}
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			registry.ReportResult(serviceName, endpoint, false)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"bad gateway","message":"upstream unavailable"}`))
+		}
+
+		registry.Acquire(serviceName, endpoint)
+		defer registry.Release(serviceName, endpoint)
+
+		cb := cbPool.Get(serviceName, endpoint)
+		cb.Middleware(proxy).ServeHTTP(w, r)
 	}
 }
 
@@ -178,3 +317,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
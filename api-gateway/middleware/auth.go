@@ -4,18 +4,35 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type JWTMiddleware struct {
-	secretKey []byte
+	jwks        *JWKSClient
+	redis       *redis.Client
+	idleTimeout time.Duration
+	revoked     *revocationCache
 }
 
-func NewJWTMiddleware(secret string) *JWTMiddleware {
+// NewJWTMiddleware builds a middleware that validates RS256 tokens against
+// the auth service's published JWKS and, when a Redis client is provided,
+// enforces a sliding idle-timeout per token jti and consults the revoked-jti
+// blacklist and token_version counter auth-service maintains, both fronted
+// by a small local LRU so revocation checks don't cost a Redis hit on every
+// request.
+func NewJWTMiddleware(jwksURL string, redisClient *redis.Client, idleTimeout time.Duration) *JWTMiddleware {
 	return &JWTMiddleware{
-		secretKey: []byte(secret),
+		jwks:        NewJWKSClient(jwksURL),
+		redis:       redisClient,
+		idleTimeout: idleTimeout,
+		revoked:     newRevocationCache(10000, 30*time.Second),
 	}
 }
 
@@ -39,10 +56,15 @@ func (jm *JWTMiddleware) Authenticate(next http.Handler) http.Handler {
 		// Parse and validate token
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return jm.secretKey, nil
+
+			kid, ok := token.Header["kid"].(string)
+			if !ok {
+				return nil, fmt.Errorf("token missing kid header")
+			}
+			return jm.jwks.Key(kid)
 		})
 
 		if err != nil {
@@ -56,18 +78,122 @@ func (jm *JWTMiddleware) Authenticate(next http.Handler) http.Handler {
 		}
 
 		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			// Add user info to context
-			ctx := context.WithValue(r.Context(), "user_id", claims["user_id"])
-			ctx = context.WithValue(ctx, "email", claims["email"])
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
 			jm.unauthorized(w, "invalid token claims")
 			return
 		}
+
+		if jm.redis != nil {
+			userIDFloat, ok := claims["user_id"].(float64)
+			if !ok {
+				jm.unauthorized(w, "invalid token claims")
+				return
+			}
+			userID := strconv.FormatInt(int64(userIDFloat), 10)
+			jti, _ := claims["jti"].(string)
+
+			if jti != "" {
+				revokedJTI, err := jm.isJTIRevoked(r.Context(), jti)
+				if err != nil {
+					jm.unauthorized(w, "failed to validate session")
+					return
+				}
+				if revokedJTI {
+					jm.unauthorized(w, "token has been revoked")
+					return
+				}
+			}
+
+			if tv, ok := claims["tv"].(float64); ok {
+				current, err := jm.isTokenVersionCurrent(r.Context(), userID, int64(tv))
+				if err != nil {
+					jm.unauthorized(w, "failed to validate session")
+					return
+				}
+				if !current {
+					jm.unauthorized(w, "token has been revoked")
+					return
+				}
+			}
+
+			if jti != "" {
+				active, err := jm.checkIdleTimeout(r.Context(), userID, jti)
+				if err != nil {
+					jm.unauthorized(w, "failed to validate session")
+					return
+				}
+				if !active {
+					jm.unauthorized(w, "session idle timeout exceeded")
+					return
+				}
+			}
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(
+			attribute.String("user_id", fmt.Sprintf("%v", claims["user_id"])),
+		)
+
+		// Add user info to context
+		ctx := context.WithValue(r.Context(), "user_id", claims["user_id"])
+		ctx = context.WithValue(ctx, "email", claims["email"])
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// isJTIRevoked checks the revoked-jti blacklist auth-service writes to on
+// logout or session revocation, via a local LRU in front of Redis.
+func (jm *JWTMiddleware) isJTIRevoked(ctx context.Context, jti string) (bool, error) {
+	cacheKey := "jti:" + jti
+	if revoked, ok := jm.revoked.get(cacheKey); ok {
+		return revoked, nil
+	}
+
+	exists, err := jm.redis.Exists(ctx, fmt.Sprintf("revoked:jti:%s", jti)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	revoked := exists == 1
+	jm.revoked.set(cacheKey, revoked)
+	return revoked, nil
+}
+
+// isTokenVersionCurrent checks a token's tv claim against the user's current
+// token_version counter, which auth-service bumps to invalidate every token
+// a user holds at once (e.g. an admin-initiated lockout).
+func (jm *JWTMiddleware) isTokenVersionCurrent(ctx context.Context, userID string, tokenVersion int64) (bool, error) {
+	cacheKey := fmt.Sprintf("tv:%s:%d", userID, tokenVersion)
+	if current, ok := jm.revoked.get(cacheKey); ok {
+		return current, nil
+	}
+
+	currentVersion, err := jm.redis.Get(ctx, fmt.Sprintf("user:token_version:%s", userID)).Int64()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	valid := tokenVersion >= currentVersion
+	jm.revoked.set(cacheKey, valid)
+	return valid, nil
+}
+
+// checkIdleTimeout reports whether the token's activity marker is still within
+// its sliding window, bumping it forward when it is.
+func (jm *JWTMiddleware) checkIdleTimeout(ctx context.Context, userID, jti string) (bool, error) {
+	key := fmt.Sprintf("token:activity:%s:%s", userID, jti)
+
+	err := jm.redis.Get(ctx, key).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, jm.redis.Set(ctx, key, time.Now().Unix(), jm.idleTimeout).Err()
+}
+
 func (jm *JWTMiddleware) unauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
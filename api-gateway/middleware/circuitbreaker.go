@@ -1,64 +1,294 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// CircuitBreakerConfig controls everything about how one upstream's breaker
+// behaves. Each gateway route gets its own, loaded from env vars so
+// auth-service/go-api/python-processor can be tuned independently without a
+// code change.
+type CircuitBreakerConfig struct {
+	Name string
+
+	// gobreaker tuning
+	MaxRequests      uint32
+	Interval         time.Duration
+	Timeout          time.Duration
+	MinRequests      uint32
+	FailureThreshold float64
+
+	// RetriableStatusCodes always count as a breaker failure.
+	RetriableStatusCodes []int
+	// ExcludedPaths lists request paths where a bare 500 is expected (e.g. a
+	// validation error the upstream reports as a 500) and shouldn't count
+	// against the breaker.
+	ExcludedPaths []string
+
+	// ConcurrencyLimit bounds how many requests can be in flight to this
+	// upstream at once; it adapts down to MinConcurrency based on observed
+	// latency, shedding load before the breaker would trip on failure ratio.
+	ConcurrencyLimit int
+	MinConcurrency   int
+
+	// HedgeDelay is how long an idempotent GET waits for the primary
+	// request before firing a second, racing attempt. Zero disables hedging.
+	HedgeDelay time.Duration
+}
+
+// DefaultCircuitBreakerConfig returns sane defaults for a service: 502/503/504
+// always trip the breaker, nothing is excluded, and hedging is off until a
+// delay is configured.
+func DefaultCircuitBreakerConfig(serviceName string) CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Name:                 serviceName,
+		MaxRequests:          3,
+		Interval:             time.Minute,
+		Timeout:              30 * time.Second,
+		MinRequests:          3,
+		FailureThreshold:     0.6,
+		RetriableStatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		ConcurrencyLimit:     64,
+		MinConcurrency:       8,
+		HedgeDelay:           0,
+	}
+}
+
+// LoadCircuitBreakerConfig starts from DefaultCircuitBreakerConfig and
+// applies any CB_<SERVICE>_* env var overrides, e.g. CB_AUTH_SERVICE_TIMEOUT_SECONDS
+// or CB_GO_API_HEDGE_DELAY_MS for service "go-api".
+func LoadCircuitBreakerConfig(serviceName string) CircuitBreakerConfig {
+	cfg := DefaultCircuitBreakerConfig(serviceName)
+	prefix := "CB_" + envPrefix(serviceName) + "_"
+
+	if v := os.Getenv(prefix + "MAX_REQUESTS"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+			cfg.MaxRequests = uint32(n)
+		}
+	}
+	if v := os.Getenv(prefix + "INTERVAL_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Interval = time.Duration(n) * time.Second
+		}
+	}
+	if v := os.Getenv(prefix + "TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Timeout = time.Duration(n) * time.Second
+		}
+	}
+	if v := os.Getenv(prefix + "MIN_REQUESTS"); v != "" {
+		if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+			cfg.MinRequests = uint32(n)
+		}
+	}
+	if v := os.Getenv(prefix + "FAILURE_THRESHOLD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.FailureThreshold = f
+		}
+	}
+	if v := os.Getenv(prefix + "CONCURRENCY_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.ConcurrencyLimit = n
+		}
+	}
+	if v := os.Getenv(prefix + "MIN_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MinConcurrency = n
+		}
+	}
+	if v := os.Getenv(prefix + "HEDGE_DELAY_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.HedgeDelay = time.Duration(n) * time.Millisecond
+		}
+	}
+	if v := os.Getenv(prefix + "EXCLUDED_PATHS"); v != "" {
+		cfg.ExcludedPaths = strings.Split(v, ",")
+	}
+
+	return cfg
+}
+
+func envPrefix(serviceName string) string {
+	return strings.ToUpper(strings.ReplaceAll(serviceName, "-", "_"))
+}
+
+// isFailure classifies a response for the breaker. 502/503/504 (or whatever
+// RetriableStatusCodes configures) always count; any other 5xx counts unless
+// the path is in ExcludedPaths.
+func (c CircuitBreakerConfig) isFailure(path string, status int) bool {
+	for _, code := range c.RetriableStatusCodes {
+		if status == code {
+			return true
+		}
+	}
+
+	if status < 500 {
+		return false
+	}
+
+	for _, excluded := range c.ExcludedPaths {
+		if excluded == path {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	cbState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Current circuit breaker state per service (0=closed, 1=half-open, 2=open)",
+	}, []string{"service"})
+	cbTripsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_trips_total",
+		Help: "Number of times a circuit breaker has tripped open",
+	}, []string{"service"})
+	cbShortCircuitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_short_circuited_total",
+		Help: "Requests rejected because the circuit breaker was open",
+	}, []string{"service"})
+)
+
+func init() {
+	prometheus.MustRegister(cbState, cbTripsTotal, cbShortCircuitedTotal)
+}
+
+// CircuitBreaker wraps gobreaker with an adaptive concurrency limiter in
+// front of it (so a slow upstream sheds load before it trips the breaker on
+// failure ratio alone) and optional request hedging for idempotent GETs.
 type CircuitBreaker struct {
-	breaker *gobreaker.CircuitBreaker
-	name    string
+	breaker     *gobreaker.CircuitBreaker
+	name        string
+	config      CircuitBreakerConfig
+	concurrency *concurrencyLimiter
 }
 
 func NewCircuitBreaker(serviceName string) *CircuitBreaker {
+	return NewCircuitBreakerWithConfig(LoadCircuitBreakerConfig(serviceName))
+}
+
+func NewCircuitBreakerWithConfig(config CircuitBreakerConfig) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		name:        config.Name,
+		config:      config,
+		concurrency: newConcurrencyLimiter(config.ConcurrencyLimit, config.MinConcurrency, config.ConcurrencyLimit),
+	}
+
 	settings := gobreaker.Settings{
-		Name:        serviceName,
-		MaxRequests: 3,
-		Interval:    time.Minute,
-		Timeout:     30 * time.Second,
+		Name:        config.Name,
+		MaxRequests: config.MaxRequests,
+		Interval:    config.Interval,
+		Timeout:     config.Timeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 3 && failureRatio >= 0.6
+			return counts.Requests >= config.MinRequests && failureRatio >= config.FailureThreshold
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
-			// Log state changes
-			// log.Printf("Circuit breaker '%s' changed from %s to %s", name, from, to)
+			cbState.WithLabelValues(name).Set(float64(to))
+			if to == gobreaker.StateOpen {
+				cbTripsTotal.WithLabelValues(name).Inc()
+			}
 		},
 	}
 
-	return &CircuitBreaker{
-		breaker: gobreaker.NewCircuitBreaker(settings),
-		name:    serviceName,
-	}
+	cb.breaker = gobreaker.NewCircuitBreaker(settings)
+	return cb
 }
 
 func (cb *CircuitBreaker) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(attribute.String("breaker.state", cb.breaker.State().String()))
+
+		if !cb.concurrency.tryAcquire() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"service unavailable","message":"too many concurrent requests to upstream"}`))
+			return
+		}
+
+		start := time.Now()
 		_, err := cb.breaker.Execute(func() (interface{}, error) {
-			// Create a custom response writer to capture status code
-			crw := &customResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-			next.ServeHTTP(crw, r)
-			
-			// If status code indicates failure, return error
-			if crw.statusCode >= 500 {
-				return nil, http.ErrAbortHandler
+			if r.Method == http.MethodGet && cb.config.HedgeDelay > 0 {
+				return nil, cb.serveHedged(w, r, next)
 			}
-			return nil, nil
+			return nil, cb.serveOnce(w, r, next)
 		})
+		cb.concurrency.release(time.Since(start))
 
-		if err != nil {
-			if err == gobreaker.ErrOpenState {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusServiceUnavailable)
-				w.Write([]byte(`{"error":"service unavailable","message":"circuit breaker is open"}`))
-				return
-			}
+		if err == gobreaker.ErrOpenState {
+			cbShortCircuitedTotal.WithLabelValues(cb.name).Inc()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"service unavailable","message":"circuit breaker is open"}`))
 		}
 	})
 }
 
+// serveOnce runs the upstream once and reports a breaker failure if the
+// response status is one the config classifies as a failure.
+func (cb *CircuitBreaker) serveOnce(w http.ResponseWriter, r *http.Request, next http.Handler) error {
+	crw := &customResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	next.ServeHTTP(crw, r)
+
+	if cb.config.isFailure(r.URL.Path, crw.statusCode) {
+		return http.ErrAbortHandler
+	}
+	return nil
+}
+
+// serveHedged races two attempts at the request: the primary starts
+// immediately, and a second fires after HedgeDelay if the primary hasn't
+// answered yet. Whichever completes first is written to the real response;
+// the loser's context is cancelled so it doesn't do useless work. Only meant
+// to be used for idempotent GETs.
+func (cb *CircuitBreaker) serveHedged(w http.ResponseWriter, r *http.Request, next http.Handler) error {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	results := make(chan *bufferedResponseWriter, 2)
+	attempt := func() {
+		resp := newBufferedResponseWriter()
+		next.ServeHTTP(resp, r.WithContext(ctx))
+		select {
+		case results <- resp:
+		case <-ctx.Done():
+		}
+	}
+
+	go attempt()
+
+	timer := time.NewTimer(cb.config.HedgeDelay)
+	defer timer.Stop()
+
+	var winner *bufferedResponseWriter
+	select {
+	case winner = <-results:
+	case <-timer.C:
+		go attempt()
+		winner = <-results
+	}
+	cancel()
+
+	winner.copyTo(w)
+	if cb.config.isFailure(r.URL.Path, winner.statusCode) {
+		return http.ErrAbortHandler
+	}
+	return nil
+}
+
 type customResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -68,3 +298,92 @@ func (crw *customResponseWriter) WriteHeader(code int) {
 	crw.statusCode = code
 	crw.ResponseWriter.WriteHeader(code)
 }
+
+// bufferedResponseWriter captures a response in memory so two hedged
+// attempts can race without either one writing straight to the real
+// http.ResponseWriter.
+type bufferedResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (b *bufferedResponseWriter) Header() http.Header { return b.header }
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponseWriter) WriteHeader(code int) { b.statusCode = code }
+
+func (b *bufferedResponseWriter) copyTo(w http.ResponseWriter) {
+	for key, values := range b.header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.WriteHeader(b.statusCode)
+	w.Write(b.body.Bytes())
+}
+
+// concurrencyLimiter bounds in-flight requests to an upstream and adapts the
+// limit based on observed latency: roughly Little's Law (concurrency ~=
+// throughput * latency), approximated with an additive-increase /
+// multiplicative-decrease adjustment rather than tracking exact throughput.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	min      int
+	max      int
+	inFlight int
+
+	targetLatency time.Duration
+}
+
+func newConcurrencyLimiter(initial, min, max int) *concurrencyLimiter {
+	if initial < min {
+		initial = min
+	}
+	return &concurrencyLimiter{
+		limit:         initial,
+		min:           min,
+		max:           max,
+		targetLatency: 200 * time.Millisecond,
+	}
+}
+
+// tryAcquire reports whether a slot was available. It never blocks: shedding
+// load immediately is the point, rather than queuing requests behind an
+// upstream that's already falling behind.
+func (l *concurrencyLimiter) tryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= l.limit {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+// release records how long the request took and nudges the limit: latency
+// under target grows it by one (additive increase), latency over target
+// shrinks it by a quarter (multiplicative decrease).
+func (l *concurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	if latency > l.targetLatency {
+		newLimit := l.limit - l.limit/4
+		if newLimit < l.min {
+			newLimit = l.min
+		}
+		l.limit = newLimit
+	} else if l.limit < l.max {
+		l.limit++
+	}
+}
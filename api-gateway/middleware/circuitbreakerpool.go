@@ -0,0 +1,35 @@
+package middleware
+
+import "sync"
+
+// CircuitBreakerPool lazily creates one CircuitBreaker per backend instance
+// of a service, so a failing instance tripping its breaker only takes that
+// instance out of rotation rather than short-circuiting the whole service.
+// Every instance of a service shares that service's CB_<SERVICE>_* settings.
+type CircuitBreakerPool struct {
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewCircuitBreakerPool() *CircuitBreakerPool {
+	return &CircuitBreakerPool{breakers: make(map[string]*CircuitBreaker)}
+}
+
+// Get returns the CircuitBreaker for a specific backend instance of
+// serviceName, creating it on first use.
+func (p *CircuitBreakerPool) Get(serviceName, endpoint string) *CircuitBreaker {
+	key := serviceName + "|" + endpoint
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cb, ok := p.breakers[key]; ok {
+		return cb
+	}
+
+	config := LoadCircuitBreakerConfig(serviceName)
+	config.Name = key
+	cb := NewCircuitBreakerWithConfig(config)
+	p.breakers[key] = cb
+	return cb
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKSClient fetches and caches the auth service's public signing keys so
+// the gateway can verify RS256 tokens without holding any secret. Keys are
+// cached for ttl; an unknown kid forces an immediate refresh, which is how
+// a key rotation on the auth service side gets picked up here.
+type JWKSClient struct {
+	url        string
+	httpClient *http.Client
+	ttl        time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKSClient(url string) *JWKSClient {
+	return &JWKSClient{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		ttl:        5 * time.Minute,
+		keys:       make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key returns the public key published under kid, refreshing the cached
+// JWKS document if it's stale or the kid isn't known yet.
+func (c *JWKSClient) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.ttl
+	c.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok = c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return key, nil
+}
+
+func (c *JWKSClient) refresh() error {
+	resp, err := c.httpClient.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc jwks
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := parseRSAPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+func parseRSAPublicKey(nEncoded, eEncoded string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(nEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode n: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(eEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode e: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
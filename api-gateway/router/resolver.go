@@ -0,0 +1,112 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Resolver discovers the current set of live backend addresses for a
+// logical service, so ServiceRegistry can refresh its endpoint pool without
+// a restart.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// StaticResolver always returns the same fixed list, e.g. parsed once from
+// a config file or a GO_API_SERVICE_URLS-style env var.
+type StaticResolver struct {
+	URLs []string
+}
+
+func (r *StaticResolver) Resolve(ctx context.Context) ([]string, error) {
+	return r.URLs, nil
+}
+
+// DNSSRVResolver resolves backends from a DNS SRV record, the way services
+// are typically discovered behind a Kubernetes headless service.
+type DNSSRVResolver struct {
+	Service string
+	Proto   string
+	Name    string
+	Scheme  string
+}
+
+func (r *DNSSRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, r.Service, r.Proto, r.Name)
+	if err != nil {
+		return nil, fmt.Errorf("resolve SRV records for %s: %w", r.Name, err)
+	}
+
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, rec := range records {
+		target := strings.TrimSuffix(rec.Target, ".")
+		urls = append(urls, fmt.Sprintf("%s://%s:%d", scheme, target, rec.Port))
+	}
+	return urls, nil
+}
+
+// ConsulResolver resolves backends from a Consul catalog, returning only
+// instances currently passing their health checks.
+type ConsulResolver struct {
+	ConsulAddr  string
+	ServiceName string
+	Scheme      string
+
+	httpClient *http.Client
+}
+
+func NewConsulResolver(consulAddr, serviceName string) *ConsulResolver {
+	return &ConsulResolver{
+		ConsulAddr:  consulAddr,
+		ServiceName: serviceName,
+		Scheme:      "http",
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type consulServiceEntry struct {
+	Service struct {
+		Address string
+		Port    int
+	} `json:"Service"`
+}
+
+func (r *ConsulResolver) Resolve(ctx context.Context) ([]string, error) {
+	reqURL := fmt.Sprintf("%s/v1/health/service/%s?passing=true", strings.TrimRight(r.ConsulAddr, "/"), r.ServiceName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query consul catalog for %s: %w", r.ServiceName, err)
+	}
+	defer resp.Body.Close()
+
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decode consul catalog response for %s: %w", r.ServiceName, err)
+	}
+
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	urls := make([]string, 0, len(entries))
+	for _, e := range entries {
+		urls = append(urls, fmt.Sprintf("%s://%s:%d", scheme, e.Service.Address, e.Service.Port))
+	}
+	return urls, nil
+}
@@ -1,52 +1,359 @@
 package router
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"sync"
+	"time"
 )
 
-// ServiceRegistry manages backend service endpoints
+// Endpoint is a single backend instance tracked by a ServiceRegistry.
+type Endpoint struct {
+	URL       string
+	Weight    int
+	Healthy   bool
+	InFlight  int
+	LastCheck time.Time
+	FailCount int
+
+	successCount  int
+	currentWeight int
+}
+
+// Strategy picks one healthy endpoint out of a pool.
+type Strategy interface {
+	Pick(endpoints []*Endpoint) *Endpoint
+}
+
+// RoundRobin cycles through endpoints in order.
+type RoundRobin struct {
+	next int
+}
+
+func (s *RoundRobin) Pick(endpoints []*Endpoint) *Endpoint {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	e := endpoints[s.next%len(endpoints)]
+	s.next++
+	return e
+}
+
+// WeightedRoundRobin implements smooth weighted round-robin: each pick adds
+// an endpoint's weight to its running currentWeight, selects the highest, and
+// subtracts the total weight from it.
+type WeightedRoundRobin struct{}
+
+func (s *WeightedRoundRobin) Pick(endpoints []*Endpoint) *Endpoint {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	totalWeight := 0
+	var best *Endpoint
+	for _, e := range endpoints {
+		weight := e.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		totalWeight += weight
+		e.currentWeight += weight
+		if best == nil || e.currentWeight > best.currentWeight {
+			best = e
+		}
+	}
+
+	best.currentWeight -= totalWeight
+	return best
+}
+
+// LeastConnections picks the endpoint with the fewest in-flight requests.
+type LeastConnections struct{}
+
+func (s *LeastConnections) Pick(endpoints []*Endpoint) *Endpoint {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	best := endpoints[0]
+	for _, e := range endpoints[1:] {
+		if e.InFlight < best.InFlight {
+			best = e
+		}
+	}
+	return best
+}
+
+// Random picks a pseudo-random endpoint based on the current time.
+type Random struct{}
+
+func (s *Random) Pick(endpoints []*Endpoint) *Endpoint {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	return endpoints[time.Now().UnixNano()%int64(len(endpoints))]
+}
+
+// ServiceRegistry tracks a pool of endpoints per logical service, runs active
+// health checks against them, and selects a healthy endpoint per request
+// using a pluggable Strategy.
 type ServiceRegistry struct {
-	services map[string][]string
-	current  map[string]int
-	mu       sync.RWMutex
+	mu        sync.RWMutex
+	endpoints map[string][]*Endpoint
+	strategy  map[string]Strategy
+
+	healthCheckInterval time.Duration
+	unhealthyThreshold  int
+	healthyThreshold    int
+	httpClient          *http.Client
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewServiceRegistry builds a registry with a background health checker that
+// probes each endpoint's /health on the given interval, marking an endpoint
+// unhealthy after unhealthyThreshold consecutive failures and reinstating it
+// after healthyThreshold consecutive successes.
+func NewServiceRegistry(healthCheckInterval time.Duration, unhealthyThreshold, healthyThreshold int) *ServiceRegistry {
+	sr := &ServiceRegistry{
+		endpoints:           make(map[string][]*Endpoint),
+		strategy:            make(map[string]Strategy),
+		healthCheckInterval: healthCheckInterval,
+		unhealthyThreshold:  unhealthyThreshold,
+		healthyThreshold:    healthyThreshold,
+		httpClient:          &http.Client{Timeout: 5 * time.Second},
+		stop:                make(chan struct{}),
+	}
+
+	go sr.healthCheckLoop()
+	return sr
+}
+
+// RegisterService registers a service's endpoints with equal weight 1 and the
+// default RoundRobin strategy. Endpoints start healthy and are verified on
+// the next health-check tick.
+func (sr *ServiceRegistry) RegisterService(name string, urls ...string) {
+	endpoints := make([]*Endpoint, 0, len(urls))
+	for _, url := range urls {
+		endpoints = append(endpoints, &Endpoint{URL: url, Weight: 1, Healthy: true})
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.endpoints[name] = endpoints
+	if _, ok := sr.strategy[name]; !ok {
+		sr.strategy[name] = &RoundRobin{}
+	}
+}
+
+// Watch keeps a service's endpoint pool in sync with a Resolver: it resolves
+// once immediately and then again on every tick of interval, for backends
+// that come from DNS SRV, a Consul catalog, or any other pluggable source
+// instead of a fixed list.
+func (sr *ServiceRegistry) Watch(name string, resolver Resolver, interval time.Duration) {
+	sr.refresh(name, resolver)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-sr.stop:
+				return
+			case <-ticker.C:
+				sr.refresh(name, resolver)
+			}
+		}
+	}()
 }
 
-func NewServiceRegistry() *ServiceRegistry {
-	return &ServiceRegistry{
-		services: make(map[string][]string),
-		current:  make(map[string]int),
+// refresh resolves a service's current backend set and reconciles it
+// against the existing pool, preserving health/in-flight state for URLs
+// that are still present and starting new ones healthy pending their first
+// health check.
+func (sr *ServiceRegistry) refresh(name string, resolver Resolver) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	urls, err := resolver.Resolve(ctx)
+	if err != nil {
+		return
+	}
+
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	existing := make(map[string]*Endpoint, len(sr.endpoints[name]))
+	for _, e := range sr.endpoints[name] {
+		existing[e.URL] = e
+	}
+
+	updated := make([]*Endpoint, 0, len(urls))
+	for _, url := range urls {
+		if e, ok := existing[url]; ok {
+			updated = append(updated, e)
+			continue
+		}
+		updated = append(updated, &Endpoint{URL: url, Weight: 1, Healthy: true})
+	}
+
+	sr.endpoints[name] = updated
+	if _, ok := sr.strategy[name]; !ok {
+		sr.strategy[name] = &RoundRobin{}
 	}
 }
 
-// RegisterService registers a service endpoint
-func (sr *ServiceRegistry) RegisterService(name string, endpoints ...string) {
+// SetStrategy overrides the selection strategy used for a service.
+func (sr *ServiceRegistry) SetStrategy(name string, strategy Strategy) {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
-	sr.services[name] = endpoints
-	sr.current[name] = 0
+	sr.strategy[name] = strategy
 }
 
-// GetEndpoint returns the next endpoint using round-robin
-func (sr *ServiceRegistry) GetEndpoint(name string) string {
+// GetEndpoint selects the next endpoint for a service according to its
+// strategy, skipping unhealthy ones, and returns an error if none are available.
+func (sr *ServiceRegistry) GetEndpoint(name string) (string, error) {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
 
-	endpoints, exists := sr.services[name]
+	endpoints, exists := sr.endpoints[name]
 	if !exists || len(endpoints) == 0 {
-		return ""
+		return "", fmt.Errorf("no endpoints registered for service %q", name)
 	}
 
-	// Round-robin selection
-	idx := sr.current[name]
-	endpoint := endpoints[idx]
-	sr.current[name] = (idx + 1) % len(endpoints)
+	healthy := make([]*Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if e.Healthy {
+			healthy = append(healthy, e)
+		}
+	}
+	if len(healthy) == 0 {
+		return "", fmt.Errorf("no healthy endpoints available for service %q", name)
+	}
 
-	return endpoint
+	strategy := sr.strategy[name]
+	if strategy == nil {
+		strategy = &RoundRobin{}
+	}
+
+	picked := strategy.Pick(healthy)
+	if picked == nil {
+		return "", fmt.Errorf("no healthy endpoints available for service %q", name)
+	}
+	return picked.URL, nil
 }
 
-// GetAllEndpoints returns all registered endpoints for a service
-func (sr *ServiceRegistry) GetAllEndpoints(name string) []string {
+// GetAllEndpoints returns all registered endpoints for a service, healthy or not.
+func (sr *ServiceRegistry) GetAllEndpoints(name string) []*Endpoint {
 	sr.mu.RLock()
 	defer sr.mu.RUnlock()
-	return sr.services[name]
+	return sr.endpoints[name]
+}
+
+// Acquire marks an endpoint as having an in-flight request, for
+// LeastConnections accounting. Call Release once the request completes.
+func (sr *ServiceRegistry) Acquire(name, url string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if e := sr.findEndpoint(name, url); e != nil {
+		e.InFlight++
+	}
+}
+
+// Release decrements the in-flight counter set by Acquire.
+func (sr *ServiceRegistry) Release(name, url string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if e := sr.findEndpoint(name, url); e != nil && e.InFlight > 0 {
+		e.InFlight--
+	}
+}
+
+// ReportResult lets upstream handlers feed an observed success/failure back
+// into the breaker, independent of the periodic health-check loop.
+func (sr *ServiceRegistry) ReportResult(name, url string, success bool) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if e := sr.findEndpoint(name, url); e != nil {
+		sr.recordResult(e, success)
+	}
+}
+
+// Stop halts the background health-check loop.
+func (sr *ServiceRegistry) Stop() {
+	sr.once.Do(func() {
+		close(sr.stop)
+	})
+}
+
+func (sr *ServiceRegistry) findEndpoint(name, url string) *Endpoint {
+	for _, e := range sr.endpoints[name] {
+		if e.URL == url {
+			return e
+		}
+	}
+	return nil
+}
+
+func (sr *ServiceRegistry) healthCheckLoop() {
+	ticker := time.NewTicker(sr.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sr.stop:
+			return
+		case <-ticker.C:
+			sr.checkAll()
+		}
+	}
+}
+
+func (sr *ServiceRegistry) checkAll() {
+	sr.mu.Lock()
+	snapshot := make([]*Endpoint, 0)
+	for _, endpoints := range sr.endpoints {
+		snapshot = append(snapshot, endpoints...)
+	}
+	sr.mu.Unlock()
+
+	for _, e := range snapshot {
+		healthy := sr.probe(e.URL)
+
+		sr.mu.Lock()
+		e.LastCheck = time.Now()
+		sr.recordResult(e, healthy)
+		sr.mu.Unlock()
+	}
+}
+
+// recordResult updates an endpoint's consecutive success/failure streak and
+// flips Healthy once the configured threshold is crossed. Callers must hold sr.mu.
+func (sr *ServiceRegistry) recordResult(e *Endpoint, success bool) {
+	if success {
+		e.successCount++
+		e.FailCount = 0
+		if !e.Healthy && e.successCount >= sr.healthyThreshold {
+			e.Healthy = true
+		}
+		return
+	}
+
+	e.FailCount++
+	e.successCount = 0
+	if e.Healthy && e.FailCount >= sr.unhealthyThreshold {
+		e.Healthy = false
+	}
+}
+
+func (sr *ServiceRegistry) probe(url string) bool {
+	resp, err := sr.httpClient.Get(url + "/health")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
 }
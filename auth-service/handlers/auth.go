@@ -2,26 +2,39 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"auth-service/keys"
 	"auth-service/models"
+	"auth-service/sessions"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	userModel *models.UserModel
-	jwtSecret []byte
+	userModel   *models.UserModel
+	keySet      *keys.KeySet
+	sessionPool *sessions.Pool
 }
 
-func NewAuthHandler(userModel *models.UserModel, secret string) *AuthHandler {
+// NewAuthHandler builds an AuthHandler. sessionPool is the in-memory,
+// disk-backed fallback that keeps sessions alive across a Redis wipe; Redis
+// itself remains the fast path for everyday token validation. Tokens are
+// signed RS256 with keySet's active key, so the gateway and other services
+// can verify them from the published JWKS without holding a secret.
+func NewAuthHandler(userModel *models.UserModel, keySet *keys.KeySet, sessionPool *sessions.Pool) *AuthHandler {
 	return &AuthHandler{
-		userModel: userModel,
-		jwtSecret: []byte(secret),
+		userModel:   userModel,
+		keySet:      keySet,
+		sessionPool: sessionPool,
 	}
 }
 
@@ -119,25 +132,52 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate tokens
-	accessToken, err := h.generateAccessToken(user.ID, user.Email)
+	h.issueTokenPair(w, ctx, user.ID, user.Email)
+}
+
+// issueTokenPair generates an access/refresh token pair for an already-
+// authenticated user, registers the session, and writes the same response
+// Login does. Shared with every AuthProvider's callback handler so a
+// provider login ends the same way a local one does.
+func (h *AuthHandler) issueTokenPair(w http.ResponseWriter, ctx context.Context, userID int64, email string) {
+	jti, err := generateJTI()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to generate token id")
+		return
+	}
+
+	accessToken, err := h.generateAccessToken(ctx, userID, email, jti)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "failed to generate access token")
 		return
 	}
 
-	refreshToken, err := h.generateRefreshToken(user.ID, user.Email)
+	refreshToken, err := h.generateRefreshToken(userID, email)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "failed to generate refresh token")
 		return
 	}
 
 	// Store refresh token in Redis
-	if err := h.userModel.StoreRefreshToken(ctx, user.ID, refreshToken); err != nil {
+	if err := h.userModel.StoreRefreshToken(ctx, userID, refreshToken); err != nil {
 		h.sendError(w, http.StatusInternalServerError, "failed to store refresh token")
 		return
 	}
 
+	// Register the access token's jti; when multi-login is disabled this also
+	// revokes every other session the user currently has open.
+	if err := h.userModel.RegisterToken(ctx, userID, jti); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to register session")
+		return
+	}
+	h.sessionPool.Put(&sessions.Session{
+		JTI:        jti,
+		UserID:     userID,
+		Email:      email,
+		IssuedAt:   time.Now(),
+		LastAccess: time.Now(),
+	})
+
 	h.sendJSON(w, http.StatusOK, TokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -154,9 +194,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse refresh token
-	token, err := jwt.Parse(req.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		return h.jwtSecret, nil
-	})
+	token, err := jwt.Parse(req.RefreshToken, h.keySet.Keyfunc)
 
 	if err != nil || !token.Valid {
 		h.sendError(w, http.StatusUnauthorized, "invalid refresh token")
@@ -180,12 +218,30 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate new access token
-	accessToken, err := h.generateAccessToken(userID, email)
+	jti, err := generateJTI()
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to generate token id")
+		return
+	}
+
+	accessToken, err := h.generateAccessToken(ctx, userID, email, jti)
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "failed to generate access token")
 		return
 	}
 
+	if err := h.userModel.RegisterToken(ctx, userID, jti); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to register session")
+		return
+	}
+	h.sessionPool.Put(&sessions.Session{
+		JTI:        jti,
+		UserID:     userID,
+		Email:      email,
+		IssuedAt:   time.Now(),
+		LastAccess: time.Now(),
+	})
+
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{
 		"accessToken": accessToken,
 		"expiresIn":   900,
@@ -201,14 +257,24 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse token to get user ID
-	token, _ := jwt.Parse(req.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		return h.jwtSecret, nil
-	})
+	token, err := jwt.Parse(req.RefreshToken, h.keySet.Keyfunc)
+	if err != nil || !token.Valid {
+		h.sendError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
 		userID := int64(claims["user_id"].(float64))
 		ctx := context.Background()
 		h.userModel.RevokeRefreshToken(ctx, userID)
+
+		jtis, err := h.userModel.ListActiveTokens(ctx, userID)
+		if err == nil {
+			for _, jti := range jtis {
+				h.sessionPool.Delete(jti)
+			}
+		}
+		h.userModel.RevokeAllTokens(ctx, userID)
 	}
 
 	h.sendJSON(w, http.StatusOK, map[string]string{"message": "logged out successfully"})
@@ -229,36 +295,109 @@ func (h *AuthHandler) VerifyToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tokenString := parts[1]
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return h.jwtSecret, nil
-	})
+	token, err := jwt.Parse(tokenString, h.keySet.Keyfunc)
 
 	if err != nil || !token.Valid {
 		h.sendError(w, http.StatusUnauthorized, "invalid token")
 		return
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		h.sendJSON(w, http.StatusOK, map[string]interface{}{
-			"valid":   true,
-			"user_id": claims["user_id"],
-			"email":   claims["email"],
-		})
-	} else {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
 		h.sendError(w, http.StatusUnauthorized, "invalid token claims")
+		return
 	}
+
+	userID := int64(claims["user_id"].(float64))
+	if jti, _ := claims["jti"].(string); jti != "" && !h.sessionActive(r.Context(), userID, jti) {
+		h.sendError(w, http.StatusUnauthorized, "session is no longer active")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"valid":   true,
+		"user_id": claims["user_id"],
+		"email":   claims["email"],
+	})
+}
+
+// sessionActive reports whether jti's idle window hasn't lapsed. Redis is the
+// fast path; if it's unreachable, this falls back to the in-memory session
+// pool so a cold Redis doesn't spuriously invalidate every live session.
+func (h *AuthHandler) sessionActive(ctx context.Context, userID int64, jti string) bool {
+	active, err := h.userModel.IsTokenActive(ctx, userID, jti)
+	if err == nil {
+		h.sessionPool.Touch(jti)
+		return active
+	}
+
+	_, ok := h.sessionPool.Get(jti)
+	return ok
+}
+
+// RevokeUserTokens invalidates every token a user currently holds, including
+// ones the per-jti blacklist wouldn't catch on its own (e.g. a session
+// restored from a stale disk snapshot), by bumping their token_version.
+// Intended for admin-initiated lockout, e.g. after a compromised account.
+func (h *AuthHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	ctx := r.Context()
+	jtis, err := h.userModel.ListActiveTokens(ctx, userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+
+	if _, err := h.userModel.BumpTokenVersion(ctx, userID); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+	if err := h.userModel.RevokeAllTokens(ctx, userID); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to revoke tokens")
+		return
+	}
+	h.userModel.RevokeRefreshToken(ctx, userID)
+
+	for _, jti := range jtis {
+		h.sessionPool.Delete(jti)
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "all tokens revoked"})
 }
 
-func (h *AuthHandler) generateAccessToken(userID int64, email string) (string, error) {
+func (h *AuthHandler) generateAccessToken(ctx context.Context, userID int64, email, jti string) (string, error) {
+	tokenVersion, err := h.userModel.TokenVersion(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+		"jti":     jti,
+		"tv":      tokenVersion,
+		"exp":     time.Now().Add(models.AccessTokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.jwtSecret)
+	active := h.keySet.Active()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = active.KID
+	return token.SignedString(active.Private)
+}
+
+// generateJTI returns a random 128-bit hex token identifier.
+func generateJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 func (h *AuthHandler) generateRefreshToken(userID int64, email string) (string, error) {
@@ -269,8 +408,10 @@ func (h *AuthHandler) generateRefreshToken(userID int64, email string) (string,
 		"iat":     time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.jwtSecret)
+	active := h.keySet.Active()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = active.KID
+	return token.SignedString(active.Private)
 }
 
 func (h *AuthHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
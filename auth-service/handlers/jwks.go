@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"auth-service/keys"
+)
+
+// JWKSHandler serves the public half of the active (and, during rotation,
+// previous) signing key so other services can verify tokens without
+// holding any secret.
+type JWKSHandler struct {
+	keySet *keys.KeySet
+}
+
+func NewJWKSHandler(keySet *keys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keySet: keySet}
+}
+
+func (h *JWKSHandler) ServeJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.keySet.JWKS())
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"auth-service/providers"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ProviderLoginHandler exposes /{provider}/login and /{provider}/callback
+// for every registered auth provider, handing off to AuthHandler's token
+// issuance once a provider resolves the caller to a local user, so a
+// provider login ends with the same JWT pair Login produces.
+type ProviderLoginHandler struct {
+	auth      *AuthHandler
+	providers map[string]providers.Provider
+	indieAuth *providers.IndieAuthProvider
+}
+
+// NewProviderLoginHandler builds a handler over registered, the non-IndieAuth
+// providers available (local, and any configured OIDC issuers). indieAuth is
+// handled separately since it needs the caller's identity URL before it can
+// even discover its endpoints; it may be nil if IndieAuth isn't configured.
+func NewProviderLoginHandler(auth *AuthHandler, registered []providers.Provider, indieAuth *providers.IndieAuthProvider) *ProviderLoginHandler {
+	byName := make(map[string]providers.Provider, len(registered))
+	for _, p := range registered {
+		byName[p.Name()] = p
+	}
+	return &ProviderLoginHandler{auth: auth, providers: byName, indieAuth: indieAuth}
+}
+
+// Login redirects to the provider's authorization endpoint for
+// redirect-based providers.
+func (h *ProviderLoginHandler) Login(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+
+	if h.indieAuth != nil && name == h.indieAuth.Name() {
+		identityURL := r.URL.Query().Get("me")
+		if identityURL == "" {
+			h.auth.sendError(w, http.StatusBadRequest, "me is required")
+			return
+		}
+
+		state, err := newState(identityURL)
+		if err != nil {
+			h.auth.sendError(w, http.StatusInternalServerError, "failed to generate state")
+			return
+		}
+
+		authURL, err := h.indieAuth.BeginLogin(r.Context(), identityURL, state)
+		if err != nil {
+			h.auth.sendError(w, http.StatusBadRequest, "failed to discover identity url")
+			return
+		}
+		setStateCookie(w, name, state)
+		http.Redirect(w, r, authURL, http.StatusFound)
+		return
+	}
+
+	provider, ok := h.providers[name]
+	if !ok {
+		h.auth.sendError(w, http.StatusNotFound, "unknown provider")
+		return
+	}
+
+	state, err := newState("")
+	if err != nil {
+		h.auth.sendError(w, http.StatusInternalServerError, "failed to generate state")
+		return
+	}
+
+	authURL, ok := provider.AuthorizationURL(state)
+	if !ok {
+		h.auth.sendError(w, http.StatusBadRequest, "provider does not support a redirect login")
+		return
+	}
+	setStateCookie(w, name, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes a redirect-based login and issues the same JWT pair
+// Login does. The returned state is checked against the nonce Login stashed
+// in an httpOnly cookie before anything else happens, so an attacker can't
+// start their own login flow and trick a victim into completing it (login
+// CSRF/session fixation) by getting them to hit this URL with the
+// attacker's code and state.
+func (h *ProviderLoginHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+
+	if !validateStateCookie(w, r, name, state) {
+		h.auth.sendError(w, http.StatusBadRequest, "invalid or expired state")
+		return
+	}
+
+	var provider providers.Provider
+	if h.indieAuth != nil && name == h.indieAuth.Name() {
+		provider = h.indieAuth
+	} else {
+		var ok bool
+		provider, ok = h.providers[name]
+		if !ok {
+			h.auth.sendError(w, http.StatusNotFound, "unknown provider")
+			return
+		}
+	}
+
+	userID, email, err := provider.Callback(r.Context(), code, state)
+	if err != nil {
+		h.auth.sendError(w, http.StatusUnauthorized, "authentication failed")
+		return
+	}
+
+	h.auth.issueTokenPair(w, r.Context(), userID, email)
+}
+
+// newState returns a random nonce, optionally prefixed with payload so it
+// survives the redirect round trip (e.g. IndieAuth's identity URL).
+func newState(payload string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(b)
+	if payload == "" {
+		return nonce, nil
+	}
+	return payload + "|" + nonce, nil
+}
+
+// stateCookieName is per-provider so two redirect-based logins started in
+// different tabs don't clobber each other's cookie.
+func stateCookieName(provider string) string {
+	return "oauth_state_" + provider
+}
+
+// setStateCookie stashes the nonce Login issued to this browser in an
+// httpOnly cookie, so Callback can verify the provider's response actually
+// belongs to a flow this browser started.
+func setStateCookie(w http.ResponseWriter, provider, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName(provider),
+		Value:    state,
+		Path:     "/",
+		MaxAge:   300,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// validateStateCookie reports whether state matches the nonce stashed by
+// setStateCookie for this provider, and clears the cookie either way so it
+// can't be replayed.
+func validateStateCookie(w http.ResponseWriter, r *http.Request, provider, state string) bool {
+	name := stateCookieName(provider)
+	cookie, err := r.Cookie(name)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if err != nil || state == "" || cookie.Value != state {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ListSessions returns the caller's currently active token ids (jtis).
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "missing or invalid authorization header")
+		return
+	}
+
+	jtis, err := h.userModel.ListActiveTokens(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to list sessions")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":  userID,
+		"sessions": jtis,
+	})
+}
+
+// RevokeSession terminates one of the caller's sessions by jti.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userIDFromRequest(r)
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "missing or invalid authorization header")
+		return
+	}
+
+	jti := chi.URLParam(r, "jti")
+	if jti == "" {
+		h.sendError(w, http.StatusBadRequest, "jti is required")
+		return
+	}
+
+	if err := h.userModel.RevokeToken(r.Context(), userID, jti); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	h.sessionPool.Delete(jti)
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "session revoked"})
+}
+
+// userIDFromRequest extracts the user_id claim from a bearer access token.
+func (h *AuthHandler) userIDFromRequest(r *http.Request) (int64, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return 0, false
+	}
+
+	const prefix = "Bearer "
+	if len(authHeader) <= len(prefix) || authHeader[:len(prefix)] != prefix {
+		return 0, false
+	}
+	tokenString := authHeader[len(prefix):]
+
+	token, err := jwt.Parse(tokenString, h.keySet.Keyfunc)
+	if err != nil || !token.Valid {
+		return 0, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int64(userIDFloat), true
+}
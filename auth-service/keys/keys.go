@@ -0,0 +1,182 @@
+// Package keys manages the RSA key pairs auth-service signs JWTs with.
+// Tokens carry a kid header so verifiers can pick the right public key
+// without holding a shared secret, and a KeySet can hold two keys at once
+// so rotating the signing key doesn't invalidate tokens issued moments
+// before the rotation.
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyPair is a signing key together with the kid it is published under.
+type KeyPair struct {
+	KID     string
+	Private *rsa.PrivateKey
+}
+
+// LoadOrGenerateKeyPair reads a PEM-encoded RSA private key from path. If
+// path is empty, it generates a fresh 2048-bit key instead, which is fine
+// for local development but means tokens won't verify across a restart.
+func LoadOrGenerateKeyPair(path string) (*KeyPair, error) {
+	if path == "" {
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("generate signing key: %w", err)
+		}
+		return &KeyPair{KID: fingerprint(&private.PublicKey), Private: private}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		key, err2 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err2 != nil {
+			return nil, fmt.Errorf("parse signing key %s: %w", path, err)
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("signing key %s is not an RSA key", path)
+		}
+		private = rsaKey
+	}
+
+	return &KeyPair{KID: fingerprint(&private.PublicKey), Private: private}, nil
+}
+
+// fingerprint derives a stable kid from a public key so the same key always
+// publishes under the same id across restarts.
+func fingerprint(pub *rsa.PublicKey) string {
+	sum := sha256.Sum256(pub.N.Bytes())
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:16]
+}
+
+// KeySet holds the currently active signing key and, during a rotation, the
+// previous one so tokens issued before the rotation keep validating until
+// they expire.
+type KeySet struct {
+	mu       sync.RWMutex
+	active   *KeyPair
+	previous *KeyPair
+}
+
+// NewKeySet builds a KeySet with the given active key and an optional
+// previous key still eligible for verification.
+func NewKeySet(active, previous *KeyPair) *KeySet {
+	return &KeySet{active: active, previous: previous}
+}
+
+// Active returns the key new tokens should be signed with.
+func (ks *KeySet) Active() *KeyPair {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.active
+}
+
+// Rotate promotes newKey to active, demoting the current active key to
+// previous. Live sessions signed with the old active key keep validating
+// until they expire; anything signed before that is no longer accepted.
+func (ks *KeySet) Rotate(newKey *KeyPair) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.previous = ks.active
+	ks.active = newKey
+}
+
+// Lookup finds the public key published under kid, checking both the
+// active and previous keys.
+func (ks *KeySet) Lookup(kid string) (*rsa.PublicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.active != nil && ks.active.KID == kid {
+		return &ks.active.Private.PublicKey, true
+	}
+	if ks.previous != nil && ks.previous.KID == kid {
+		return &ks.previous.Private.PublicKey, true
+	}
+	return nil, false
+}
+
+// Keyfunc is a jwt.Keyfunc that resolves the verification key from a
+// token's kid header, rejecting anything not signed with an RSA method
+// (RS256, RS384 or RS512).
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("token missing kid header")
+	}
+
+	key, ok := ks.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return key, nil
+}
+
+// JWK is a single RSA public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set, the body served from /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS builds the published key set: the active key and, while a rotation
+// is in flight, the previous key too, so in-flight tokens still verify.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	jwks := JWKS{}
+	if ks.active != nil {
+		jwks.Keys = append(jwks.Keys, toJWK(ks.active))
+	}
+	if ks.previous != nil {
+		jwks.Keys = append(jwks.Keys, toJWK(ks.previous))
+	}
+	return jwks
+}
+
+func toJWK(kp *KeyPair) JWK {
+	pub := kp.Private.PublicKey
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: kp.KID,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth-service/keys"
+	"auth-service/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// RBAC enforces role/permission checks on routes. It validates the caller's
+// bearer token itself (against the published signing keys) rather than
+// trusting a context value, since requests can reach this service directly
+// as well as through the gateway. It also checks the token against the same
+// revoked-jti blacklist and token_version counter the gateway's JWTMiddleware
+// does, so a token revoked via Logout/RevokeSession/RevokeUserTokens is
+// rejected here too rather than staying valid against this service's own
+// endpoints until it expires.
+type RBAC struct {
+	roleModel *models.RoleModel
+	keySet    *keys.KeySet
+	redis     *redis.Client
+	revoked   *revocationCache
+}
+
+func NewRBAC(roleModel *models.RoleModel, keySet *keys.KeySet, redisClient *redis.Client) *RBAC {
+	return &RBAC{
+		roleModel: roleModel,
+		keySet:    keySet,
+		redis:     redisClient,
+		revoked:   newRevocationCache(10000, 30*time.Second),
+	}
+}
+
+// RequireRole 403s unless the caller has the given role, directly or via a parent role.
+func (rb *RBAC) RequireRole(role string) func(http.Handler) http.Handler {
+	return rb.require(func(userID int64, r *http.Request) (bool, error) {
+		return rb.roleModel.HasRole(r.Context(), userID, role)
+	})
+}
+
+// RequirePermission 403s unless the caller has the given permission.
+func (rb *RBAC) RequirePermission(perm string) func(http.Handler) http.Handler {
+	return rb.require(func(userID int64, r *http.Request) (bool, error) {
+		return rb.roleModel.HasPermission(r.Context(), userID, perm)
+	})
+}
+
+func (rb *RBAC) require(check func(userID int64, r *http.Request) (bool, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := rb.userIDFromRequest(r)
+			if !ok {
+				rb.forbidden(w, http.StatusUnauthorized, "missing or invalid authorization header")
+				return
+			}
+
+			allowed, err := check(userID, r)
+			if err != nil {
+				rb.forbidden(w, http.StatusInternalServerError, "failed to check permissions")
+				return
+			}
+			if !allowed {
+				rb.forbidden(w, http.StatusForbidden, "insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rb *RBAC) userIDFromRequest(r *http.Request) (int64, bool) {
+	authHeader := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return 0, false
+	}
+
+	token, err := jwt.Parse(authHeader[len(prefix):], rb.keySet.Keyfunc)
+	if err != nil || !token.Valid {
+		return 0, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	userID := int64(userIDFloat)
+
+	if rb.redis != nil {
+		userIDStr := strconv.FormatInt(userID, 10)
+
+		if jti, _ := claims["jti"].(string); jti != "" {
+			revokedJTI, err := rb.isJTIRevoked(r.Context(), jti)
+			if err != nil || revokedJTI {
+				return 0, false
+			}
+		}
+
+		if tv, ok := claims["tv"].(float64); ok {
+			current, err := rb.isTokenVersionCurrent(r.Context(), userIDStr, int64(tv))
+			if err != nil || !current {
+				return 0, false
+			}
+		}
+	}
+
+	return userID, true
+}
+
+// isJTIRevoked checks the revoked-jti blacklist auth-service itself writes
+// to on logout or session revocation, via a local LRU in front of Redis.
+func (rb *RBAC) isJTIRevoked(ctx context.Context, jti string) (bool, error) {
+	cacheKey := "jti:" + jti
+	if revoked, ok := rb.revoked.get(cacheKey); ok {
+		return revoked, nil
+	}
+
+	exists, err := rb.redis.Exists(ctx, fmt.Sprintf("revoked:jti:%s", jti)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	revoked := exists == 1
+	rb.revoked.set(cacheKey, revoked)
+	return revoked, nil
+}
+
+// isTokenVersionCurrent checks a token's tv claim against the user's current
+// token_version counter, bumped by RevokeUserTokens to invalidate every
+// token a user holds at once.
+func (rb *RBAC) isTokenVersionCurrent(ctx context.Context, userID string, tokenVersion int64) (bool, error) {
+	cacheKey := fmt.Sprintf("tv:%s:%d", userID, tokenVersion)
+	if current, ok := rb.revoked.get(cacheKey); ok {
+		return current, nil
+	}
+
+	currentVersion, err := rb.redis.Get(ctx, fmt.Sprintf("user:token_version:%s", userID)).Int64()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	valid := tokenVersion >= currentVersion
+	rb.revoked.set(cacheKey, valid)
+	return valid, nil
+}
+
+func (rb *RBAC) forbidden(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":"%s"}`, message)
+}
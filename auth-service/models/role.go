@@ -2,24 +2,36 @@ package models
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
 type Role struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	ParentRoleID *int64    `json:"parent_role_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
+// Permission is a fine-grained action roles can be granted, e.g. "housing:read".
+type Permission struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+const roleCacheTTL = 60 * time.Second
+
 type RoleModel struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	redis *redis.Client
 }
 
-func NewRoleModel(db *pgxpool.Pool) *RoleModel {
-	return &RoleModel{db: db}
+func NewRoleModel(db *pgxpool.Pool, redis *redis.Client) *RoleModel {
+	return &RoleModel{db: db, redis: redis}
 }
 
 // GetRoleByName retrieves a role by name
@@ -77,8 +89,10 @@ func (m *RoleModel) AssignRoleToUser(ctx context.Context, userID, roleID int64)
 		ON CONFLICT (user_id, role_id) DO NOTHING
 	`
 
-	_, err := m.db.Exec(ctx, query, userID, roleID)
-	return err
+	if _, err := m.db.Exec(ctx, query, userID, roleID); err != nil {
+		return err
+	}
+	return m.invalidateRoleCache(ctx, userID)
 }
 
 // RemoveRoleFromUser removes a role from a user
@@ -88,18 +102,29 @@ func (m *RoleModel) RemoveRoleFromUser(ctx context.Context, userID, roleID int64
 		WHERE user_id = $1 AND role_id = $2
 	`
 
-	_, err := m.db.Exec(ctx, query, userID, roleID)
-	return err
+	if _, err := m.db.Exec(ctx, query, userID, roleID); err != nil {
+		return err
+	}
+	return m.invalidateRoleCache(ctx, userID)
 }
 
-// HasRole checks if a user has a specific role
+// HasRole checks if a user has a specific role, directly or through a
+// parent role in the hierarchy (e.g. "admin" implicitly grants "user").
 func (m *RoleModel) HasRole(ctx context.Context, userID int64, roleName string) (bool, error) {
 	query := `
-		SELECT EXISTS(
-			SELECT 1 FROM user_roles ur
-			INNER JOIN roles r ON ur.role_id = r.id
-			WHERE ur.user_id = $1 AND r.name = $2
+		WITH RECURSIVE role_tree AS (
+			SELECT r.id, r.name, r.parent_role_id
+			FROM roles r
+			INNER JOIN user_roles ur ON ur.role_id = r.id
+			WHERE ur.user_id = $1
+
+			UNION
+
+			SELECT parent.id, parent.name, parent.parent_role_id
+			FROM roles parent
+			INNER JOIN role_tree rt ON parent.id = rt.parent_role_id
 		)
+		SELECT EXISTS(SELECT 1 FROM role_tree WHERE name = $2)
 	`
 
 	var exists bool
@@ -110,3 +135,88 @@ func (m *RoleModel) HasRole(ctx context.Context, userID int64, roleName string)
 
 	return exists, nil
 }
+
+// HasPermission checks if a user has a permission, aggregated across every
+// role in their hierarchy (own roles plus any ancestor roles they imply).
+// Results are cached in Redis for roleCacheTTL under user:roles:<id>.
+func (m *RoleModel) HasPermission(ctx context.Context, userID int64, perm string) (bool, error) {
+	cacheKey := fmt.Sprintf("user:roles:%d", userID)
+
+	if m.redis != nil {
+		if exists, err := m.redis.Exists(ctx, cacheKey).Result(); err == nil && exists == 1 {
+			return m.redis.SIsMember(ctx, cacheKey, perm).Result()
+		}
+	}
+
+	perms, err := m.userPermissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if m.redis != nil {
+		members := make([]interface{}, len(perms)+1)
+		members[0] = "" // sentinel so the set persists even with zero real permissions
+		for i, p := range perms {
+			members[i+1] = p
+		}
+		pipe := m.redis.Pipeline()
+		pipe.SAdd(ctx, cacheKey, members...)
+		pipe.Expire(ctx, cacheKey, roleCacheTTL)
+		pipe.Exec(ctx)
+	}
+
+	for _, p := range perms {
+		if p == perm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// userPermissions resolves every permission granted by a user's roles and
+// their ancestors via the role hierarchy.
+func (m *RoleModel) userPermissions(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		WITH RECURSIVE role_tree AS (
+			SELECT r.id, r.parent_role_id
+			FROM roles r
+			INNER JOIN user_roles ur ON ur.role_id = r.id
+			WHERE ur.user_id = $1
+
+			UNION
+
+			SELECT parent.id, parent.parent_role_id
+			FROM roles parent
+			INNER JOIN role_tree rt ON parent.id = rt.parent_role_id
+		)
+		SELECT DISTINCT p.name
+		FROM permissions p
+		INNER JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id IN (SELECT id FROM role_tree)
+	`
+
+	rows, err := m.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, nil
+}
+
+// invalidateRoleCache clears the cached roles/permissions for a user after
+// an assignment change.
+func (m *RoleModel) invalidateRoleCache(ctx context.Context, userID int64) error {
+	if m.redis == nil {
+		return nil
+	}
+	return m.redis.Del(ctx, fmt.Sprintf("user:roles:%d", userID)).Err()
+}
@@ -9,6 +9,11 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// AccessTokenTTL is how long an issued access token is valid for; it also
+// bounds how long a revoked jti needs to stay on the blacklist, since the
+// token can't be accepted past this lifetime anyway.
+const AccessTokenTTL = 15 * time.Minute
+
 type User struct {
 	ID           int64     `json:"id"`
 	Email        string    `json:"email"`
@@ -20,14 +25,21 @@ type User struct {
 
 
 type UserModel struct {
-	db    *pgxpool.Pool
-	redis *redis.Client
+	db               *pgxpool.Pool
+	redis            *redis.Client
+	idleTimeout      time.Duration
+	enableMultiLogin bool
 }
 
-func NewUserModel(db *pgxpool.Pool, redis *redis.Client) *UserModel {
+// NewUserModel builds a UserModel. idleTimeout is the sliding window after which
+// an otherwise-valid access token is rejected for inactivity; enableMultiLogin
+// controls whether logging in revokes the user's other active sessions.
+func NewUserModel(db *pgxpool.Pool, redis *redis.Client, idleTimeout time.Duration, enableMultiLogin bool) *UserModel {
 	return &UserModel{
-		db:    db,
-		redis: redis,
+		db:               db,
+		redis:            redis,
+		idleTimeout:      idleTimeout,
+		enableMultiLogin: enableMultiLogin,
 	}
 }
 
@@ -101,6 +113,56 @@ func (m *UserModel) GetUserByID(ctx context.Context, id int64) (*User, error) {
 	return &user, nil
 }
 
+// FindOrCreateByProvider resolves a (provider, provider_subject) identity to
+// a local user: an existing link is returned as-is, an unlinked identity is
+// attached to an existing account with the same email, and otherwise a new
+// passwordless account is created. Assumes users carries provider and
+// provider_subject columns with a UNIQUE(provider, provider_subject)
+// constraint alongside the existing UNIQUE(email), the same way every other
+// query in this package assumes its table shape without a migration to
+// point to.
+func (m *UserModel) FindOrCreateByProvider(ctx context.Context, provider, subject, email string) (*User, error) {
+	var user User
+
+	err := m.db.QueryRow(ctx, `
+		SELECT id, email, name, created_at
+		FROM users
+		WHERE provider = $1 AND provider_subject = $2
+	`, provider, subject).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt)
+	if err == nil {
+		return &user, nil
+	}
+
+	err = m.db.QueryRow(ctx, `
+		UPDATE users SET provider = $1, provider_subject = $2
+		WHERE email = $3
+		RETURNING id, email, name, created_at
+	`, provider, subject, email).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt)
+	if err == nil {
+		return &user, nil
+	}
+
+	err = m.db.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, name, provider, provider_subject, created_at)
+		VALUES ($1, '', $2, $3, $4, $5)
+		RETURNING id, email, name, created_at
+	`, email, email, provider, subject, time.Now()).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	roleQuery := `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = 'user'
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := m.db.Exec(ctx, roleQuery, user.ID); err != nil {
+		fmt.Printf("Warning: failed to assign default role to user %d: %v\n", user.ID, err)
+	}
+
+	return &user, nil
+}
+
 // StoreRefreshToken stores a refresh token in Redis with 7 days TTL
 func (m *UserModel) StoreRefreshToken(ctx context.Context, userID int64, token string) error {
 	key := fmt.Sprintf("refresh_token:%d", userID)
@@ -122,3 +184,118 @@ func (m *UserModel) RevokeRefreshToken(ctx context.Context, userID int64) error
 	key := fmt.Sprintf("refresh_token:%d", userID)
 	return m.redis.Del(ctx, key).Err()
 }
+
+// RegisterToken records a newly issued access token jti as active for the user.
+// When multi-login is disabled, any previously active jtis are revoked first so
+// only the most recent login stays valid.
+func (m *UserModel) RegisterToken(ctx context.Context, userID int64, jti string) error {
+	tokensKey := fmt.Sprintf("user:tokens:%d", userID)
+
+	if !m.enableMultiLogin {
+		if err := m.RevokeAllTokens(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := m.redis.SAdd(ctx, tokensKey, jti).Err(); err != nil {
+		return err
+	}
+	return m.TouchTokenActivity(ctx, userID, jti)
+}
+
+// TouchTokenActivity bumps the sliding idle-timeout window for a token.
+func (m *UserModel) TouchTokenActivity(ctx context.Context, userID int64, jti string) error {
+	key := fmt.Sprintf("token:activity:%d:%s", userID, jti)
+	return m.redis.Set(ctx, key, time.Now().Unix(), m.idleTimeout).Err()
+}
+
+// IsTokenActive reports whether a token's idle window hasn't lapsed yet.
+func (m *UserModel) IsTokenActive(ctx context.Context, userID int64, jti string) (bool, error) {
+	key := fmt.Sprintf("token:activity:%d:%s", userID, jti)
+	err := m.redis.Get(ctx, key).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListActiveTokens returns the jtis currently registered as active for a user.
+func (m *UserModel) ListActiveTokens(ctx context.Context, userID int64) ([]string, error) {
+	tokensKey := fmt.Sprintf("user:tokens:%d", userID)
+	return m.redis.SMembers(ctx, tokensKey).Result()
+}
+
+// RevokeToken terminates a single session: it stops counting as active, its
+// idle-activity marker is removed, and its jti is blacklisted so the gateway
+// rejects it immediately rather than waiting on the idle timeout.
+func (m *UserModel) RevokeToken(ctx context.Context, userID int64, jti string) error {
+	tokensKey := fmt.Sprintf("user:tokens:%d", userID)
+	activityKey := fmt.Sprintf("token:activity:%d:%s", userID, jti)
+
+	pipe := m.redis.Pipeline()
+	pipe.SRem(ctx, tokensKey, jti)
+	pipe.Del(ctx, activityKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return m.BlacklistJTI(ctx, jti)
+}
+
+// BlacklistJTI marks a token id as revoked for the rest of its natural
+// lifetime. Keyed by jti alone (not userID) so the gateway can check it
+// without re-deriving the user from the token first.
+func (m *UserModel) BlacklistJTI(ctx context.Context, jti string) error {
+	key := fmt.Sprintf("revoked:jti:%s", jti)
+	return m.redis.Set(ctx, key, 1, AccessTokenTTL).Err()
+}
+
+// IsJTIBlacklisted reports whether a token id has been explicitly revoked.
+func (m *UserModel) IsJTIBlacklisted(ctx context.Context, jti string) (bool, error) {
+	exists, err := m.redis.Exists(ctx, fmt.Sprintf("revoked:jti:%s", jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// TokenVersion returns the current token_version counter for a user; access
+// tokens carry the version they were issued under, and any token issued
+// before the counter was last bumped is no longer accepted.
+func (m *UserModel) TokenVersion(ctx context.Context, userID int64) (int64, error) {
+	key := fmt.Sprintf("user:token_version:%d", userID)
+	version, err := m.redis.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// BumpTokenVersion invalidates every token issued so far for a user, even
+// ones the per-jti blacklist doesn't know about (e.g. a session restored
+// from a stale disk snapshot). Used for incident response.
+func (m *UserModel) BumpTokenVersion(ctx context.Context, userID int64) (int64, error) {
+	key := fmt.Sprintf("user:token_version:%d", userID)
+	return m.redis.Incr(ctx, key).Result()
+}
+
+// RevokeAllTokens terminates every active session for a user, e.g. on a
+// single-login login or an admin-initiated lockout.
+func (m *UserModel) RevokeAllTokens(ctx context.Context, userID int64) error {
+	jtis, err := m.ListActiveTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, jti := range jtis {
+		if err := m.RevokeToken(ctx, userID, jti); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"auth-service/models"
+)
+
+func indieAuthLinkPattern(rel string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)<link[^>]+rel=["']` + rel + `["'][^>]+href=["']([^"']+)["']`)
+}
+
+type indieAuthEndpoints struct {
+	authorization string
+	token         string
+}
+
+// IndieAuthProvider implements the IndieAuth flow: a user's own URL is
+// their identity (the provider_subject), and the authorization/token
+// endpoints are discovered per-user from <link rel="..."> tags published
+// on that URL, rather than a fixed issuer like OIDC.
+type IndieAuthProvider struct {
+	clientID    string
+	redirectURL string
+	userModel   *models.UserModel
+	httpClient  *http.Client
+}
+
+func NewIndieAuthProvider(clientID, redirectURL string, userModel *models.UserModel) *IndieAuthProvider {
+	return &IndieAuthProvider{
+		clientID:    clientID,
+		redirectURL: redirectURL,
+		userModel:   userModel,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *IndieAuthProvider) Name() string { return "indieauth" }
+
+// AuthorizationURL isn't meaningful on its own: IndieAuth's authorization
+// endpoint is discovered from the user's identity URL, which isn't known
+// until login time. Callers should use BeginLogin instead.
+func (p *IndieAuthProvider) AuthorizationURL(state string) (string, bool) {
+	return "", false
+}
+
+// BeginLogin discovers identityURL's authorization endpoint and returns
+// where to redirect the user. state carries identityURL through the
+// redirect round trip so Callback can re-discover the matching token
+// endpoint.
+func (p *IndieAuthProvider) BeginLogin(ctx context.Context, identityURL, state string) (string, error) {
+	endpoints, err := p.discover(ctx, identityURL)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.clientID},
+		"redirect_uri":  {p.redirectURL},
+		"state":         {state},
+		"me":            {identityURL},
+	}
+	return endpoints.authorization + "?" + values.Encode(), nil
+}
+
+func (p *IndieAuthProvider) Authenticate(ctx context.Context, creds Credentials) (int64, string, error) {
+	return 0, "", ErrNotSupported
+}
+
+// Callback exchanges code for the user's profile URL at the token
+// endpoint. identityURL is recovered from state since IndieAuth's
+// endpoints are per-user rather than fixed like an OIDC issuer.
+func (p *IndieAuthProvider) Callback(ctx context.Context, code, state string) (int64, string, error) {
+	identityURL, err := decodeIndieAuthState(state)
+	if err != nil {
+		return 0, "", err
+	}
+
+	endpoints, err := p.discover(ctx, identityURL)
+	if err != nil {
+		return 0, "", err
+	}
+	if endpoints.token == "" {
+		return 0, "", fmt.Errorf("no token_endpoint published on %s", identityURL)
+	}
+
+	me, err := p.redeemCode(ctx, endpoints.token, code)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// me is only trustworthy if its own published endpoints match the ones
+	// we actually used: otherwise an attacker's identity URL could discover
+	// its own authorization/token endpoints but hand back an arbitrary
+	// victim's me, impersonating them (IndieAuth confused-deputy).
+	meEndpoints, err := p.discover(ctx, me)
+	if err != nil {
+		return 0, "", fmt.Errorf("verify indieauth profile url %s: %w", me, err)
+	}
+	if meEndpoints.token != endpoints.token {
+		return 0, "", fmt.Errorf("indieauth profile url %s does not match the endpoints used for login", me)
+	}
+
+	// The identity URL is the subject, and there's no separate email to
+	// resolve, so it doubles as the linked account's email-shaped identifier.
+	user, err := p.userModel.FindOrCreateByProvider(ctx, p.Name(), me, me)
+	if err != nil {
+		return 0, "", err
+	}
+	return user.ID, user.Email, nil
+}
+
+func (p *IndieAuthProvider) discover(ctx context.Context, identityURL string) (indieAuthEndpoints, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, identityURL, nil)
+	if err != nil {
+		return indieAuthEndpoints{}, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return indieAuthEndpoints{}, fmt.Errorf("fetch identity url %s: %w", identityURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return indieAuthEndpoints{}, err
+	}
+
+	authMatch := indieAuthLinkPattern("authorization_endpoint").FindSubmatch(body)
+	if authMatch == nil {
+		return indieAuthEndpoints{}, fmt.Errorf("no authorization_endpoint link found on %s", identityURL)
+	}
+
+	endpoints := indieAuthEndpoints{authorization: string(authMatch[1])}
+	if tokenMatch := indieAuthLinkPattern("token_endpoint").FindSubmatch(body); tokenMatch != nil {
+		endpoints.token = string(tokenMatch[1])
+	}
+	return endpoints, nil
+}
+
+func (p *IndieAuthProvider) redeemCode(ctx context.Context, tokenEndpoint, code string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"client_id":    {p.clientID},
+		"redirect_uri": {p.redirectURL},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("redeem indieauth code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var tokenResp struct {
+		Me string `json:"me"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("decode indieauth token response: %w", err)
+	}
+	if tokenResp.Me == "" {
+		return "", errors.New("indieauth token response missing me")
+	}
+	return tokenResp.Me, nil
+}
+
+func decodeIndieAuthState(state string) (string, error) {
+	identityURL, _, found := strings.Cut(state, "|")
+	if !found || identityURL == "" {
+		return "", errors.New("invalid indieauth state")
+	}
+	return identityURL, nil
+}
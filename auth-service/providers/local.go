@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"context"
+	"errors"
+
+	"auth-service/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LocalProvider is the original email+password flow, authenticating
+// directly against the users table with bcrypt.
+type LocalProvider struct {
+	userModel *models.UserModel
+}
+
+func NewLocalProvider(userModel *models.UserModel) *LocalProvider {
+	return &LocalProvider{userModel: userModel}
+}
+
+func (p *LocalProvider) Name() string { return "local" }
+
+func (p *LocalProvider) AuthorizationURL(state string) (string, bool) {
+	return "", false
+}
+
+func (p *LocalProvider) Authenticate(ctx context.Context, creds Credentials) (int64, string, error) {
+	user, err := p.userModel.GetUserByEmail(ctx, creds.Email)
+	if err != nil {
+		return 0, "", errors.New("invalid credentials")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
+		return 0, "", errors.New("invalid credentials")
+	}
+
+	return user.ID, user.Email, nil
+}
+
+func (p *LocalProvider) Callback(ctx context.Context, code, state string) (int64, string, error) {
+	return 0, "", ErrNotSupported
+}
@@ -0,0 +1,41 @@
+// Package providers implements pluggable authentication: the local
+// email+password flow plus redirect-based identity providers (OIDC,
+// IndieAuth). Every provider resolves a caller to a local user id/email so
+// the rest of the login flow (token issuance, session registration) stays
+// provider-agnostic.
+package providers
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned by whichever of Authenticate/Callback doesn't
+// apply to a given provider: Authenticate for redirect-only providers
+// (OIDC, IndieAuth), Callback for the local provider.
+var ErrNotSupported = errors.New("operation not supported by this provider")
+
+// Credentials carries whatever a direct (non-redirect) Authenticate call
+// needs; only Email/Password apply to the local provider today.
+type Credentials struct {
+	Email    string
+	Password string
+}
+
+// Provider authenticates a user through some identity mechanism.
+type Provider interface {
+	Name() string
+
+	// AuthorizationURL returns where to redirect the user to start a
+	// redirect-based flow, or ok=false if the provider authenticates
+	// directly via Authenticate instead (e.g. local email+password).
+	AuthorizationURL(state string) (url string, ok bool)
+
+	// Authenticate validates credentials presented directly to the login
+	// endpoint. Providers without a direct flow return ErrNotSupported.
+	Authenticate(ctx context.Context, creds Credentials) (userID int64, email string, err error)
+
+	// Callback completes a redirect-based flow using the code/state
+	// returned to /api/v1/auth/{provider}/callback.
+	Callback(ctx context.Context, code, state string) (userID int64, email string, err error)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Scope determines what a Rule's count is keyed by.
+type Scope string
+
+const (
+	ScopeIP     Scope = "ip"
+	ScopeUser   Scope = "user"
+	ScopeRoute  Scope = "route"
+	ScopeGlobal Scope = "global"
+)
+
+// Rule is a single "<count>/<duration>" policy, e.g. "100/1m".
+type Rule struct {
+	Count  int
+	Window time.Duration
+	Scope  Scope
+}
+
+// ParseRule parses a rule in "<count>/<duration>" form.
+func ParseRule(s string) (Rule, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("invalid rate-limit rule %q: expected \"<count>/<duration>\"", s)
+	}
+
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate-limit rule %q: bad count", s)
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate-limit rule %q: bad duration", s)
+	}
+
+	return Rule{Count: count, Window: window, Scope: ScopeIP}, nil
+}
+
+// RateLimiter enforces sliding-window rate-limit policies in Redis. It
+// mirrors the Rule/Scope policy engine used at the api-gateway, so requests
+// reaching auth-service directly (or proxied through the gateway, which has
+// already applied its own policy) are still bounded here rather than left
+// unthrottled. A global rule applies to every request; per-route rules can
+// be registered with For to override or add to it for a specific route
+// pattern.
+type RateLimiter struct {
+	client *redis.Client
+	global []Rule
+
+	mu     sync.RWMutex
+	routes map[string][]Rule
+}
+
+// NewRateLimiter builds a RateLimiter that applies the given global rules to
+// every request in addition to anything registered via For.
+func NewRateLimiter(client *redis.Client, rules ...Rule) *RateLimiter {
+	return &RateLimiter{
+		client: client,
+		global: rules,
+		routes: make(map[string][]Rule),
+	}
+}
+
+// For registers additional rules scoped to a chi route pattern, e.g.
+// rl.For("/api/v1/auth/refresh", Rule{Count: 30, Window: time.Minute, Scope: ScopeIP}).
+func (rl *RateLimiter) For(routePattern string, rules ...Rule) *RateLimiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.routes[routePattern] = append(rl.routes[routePattern], rules...)
+	return rl
+}
+
+// RateLimit is the chi middleware entry point.
+func (rl *RateLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		rules := append([]Rule{}, rl.global...)
+		globalCount := len(rules)
+		// Installed with Use inside a chi sub-router, this runs before the
+		// sub-router matches its own routes, so the pattern can still be the
+		// mount's wildcard (e.g. "/api/v1/auth/*"); fall back to the request
+		// path so rules registered under a full route still apply.
+		routePattern := chi.RouteContext(ctx).RoutePattern()
+		rl.mu.RLock()
+		routeRules, ok := rl.routes[routePattern]
+		if !ok {
+			if byPath, found := rl.routes[r.URL.Path]; found {
+				routePattern, routeRules = r.URL.Path, byPath
+			}
+		}
+		rules = append(rules, routeRules...)
+		rl.mu.RUnlock()
+
+		for i, rule := range rules {
+			key := rl.scopeKey(r, rule.Scope, routePattern)
+			if i >= globalCount && rule.Scope != ScopeRoute {
+				// Count a per-route rule apart from a global rule of the same scope.
+				key += ":" + routePattern
+			}
+
+			allowed, remaining, resetAt, err := rl.allow(ctx, key, rule)
+			if err != nil {
+				// If Redis is down, fail open.
+				continue
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Count))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"rate limit exceeded","message":"too many requests"}`))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow records the current request against the sliding window for key and
+// reports whether it is within rule.Count.
+func (rl *RateLimiter) allow(ctx context.Context, key string, rule Rule) (allowed bool, remaining int, resetAt time.Time, err error) {
+	now := time.Now()
+	windowStart := now.Add(-rule.Window)
+	resetAt = now.Add(rule.Window)
+
+	pipe := rl.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+	card := pipe.ZCard(ctx, key)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return false, 0, time.Time{}, err
+	}
+
+	count := int(card.Val())
+	if count >= rule.Count {
+		return false, 0, resetAt, nil
+	}
+
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), count)
+	addPipe := rl.client.Pipeline()
+	addPipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+	addPipe.Expire(ctx, key, rule.Window)
+	if _, err = addPipe.Exec(ctx); err != nil {
+		return false, 0, time.Time{}, err
+	}
+
+	return true, rule.Count - count - 1, resetAt, nil
+}
+
+// LoginAttemptLimit returns middleware applying a dedicated, stricter policy
+// scoped per IP+email, meant for /login and /register so credential-stuffing
+// against a single account can't hide behind a shared-IP allowance.
+func (rl *RateLimiter) LoginAttemptLimit(rule Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			key := fmt.Sprintf("ratelimit:auth:%s:%s", clientIP(r), emailFromBody(r))
+
+			allowed, remaining, resetAt, err := rl.allow(ctx, key, rule)
+			if err == nil {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rule.Count))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+				if !allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())))
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusTooManyRequests)
+					w.Write([]byte(`{"error":"rate limit exceeded","message":"too many login attempts"}`))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// emailFromBody peeks the request body for an "email" field without
+// consuming it, so downstream handlers still see the full body.
+func emailFromBody(r *http.Request) string {
+	if r.Body == nil {
+		return "unknown"
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "unknown"
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Email == "" {
+		return "unknown"
+	}
+	return payload.Email
+}
+
+// scopeKey builds the Redis sorted-set key for a rule scope.
+func (rl *RateLimiter) scopeKey(r *http.Request, scope Scope, routePattern string) string {
+	switch scope {
+	case ScopeUser:
+		return fmt.Sprintf("ratelimit:user:%s", fmt.Sprintf("%v", r.Context().Value("user_id")))
+	case ScopeRoute:
+		return fmt.Sprintf("ratelimit:route:%s", routePattern)
+	case ScopeGlobal:
+		return "ratelimit:global"
+	default:
+		return fmt.Sprintf("ratelimit:ip:%s", clientIP(r))
+	}
+}
+
+// clientIP returns the caller's IP, preferring X-Real-IP as set upstream by
+// the gateway's chi RealIP middleware.
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,158 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+// Session is a single logged-in access token tracked in-memory as a fast,
+// durable fallback for when Redis is cold or has been wiped.
+type Session struct {
+	JTI        string
+	UserID     int64
+	Email      string
+	IssuedAt   time.Time
+	LastAccess time.Time
+}
+
+// Metrics is a point-in-time snapshot of pool activity.
+type Metrics struct {
+	Live      int   `json:"live"`
+	Evictions int64 `json:"evictions"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+}
+
+// Pool is an in-memory, size- and idle-bounded store of active sessions. It
+// is the durable fallback for refresh-token/session lookups: Redis remains
+// the fast path, and the pool is flushed to disk on shutdown and rehydrated
+// on startup so a cold Redis doesn't log everyone out.
+type Pool struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	maxOpen  int
+	maxIdle  time.Duration
+
+	evictions int64
+	hits      int64
+	misses    int64
+}
+
+// NewPool builds a pool that evicts sessions idle past maxIdle and never
+// holds more than maxOpen live sessions at once.
+func NewPool(maxOpen int, maxIdle time.Duration) *Pool {
+	return &Pool{
+		sessions: make(map[string]*Session),
+		maxOpen:  maxOpen,
+		maxIdle:  maxIdle,
+	}
+}
+
+// Put inserts or refreshes a session. If the pool is at capacity, the oldest
+// session by LastAccess is evicted to make room.
+func (p *Pool) Put(s *Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.sessions[s.JTI]; !exists && len(p.sessions) >= p.maxOpen {
+		p.evictOldestLocked()
+	}
+	p.sessions[s.JTI] = s
+}
+
+// Touch updates a session's LastAccess timestamp, reporting whether it was found.
+func (p *Pool) Touch(jti string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.sessions[jti]
+	if !ok {
+		p.misses++
+		return false
+	}
+	s.LastAccess = time.Now()
+	p.hits++
+	return true
+}
+
+// Get returns a copy of a live session, evicting it first if its idle window has lapsed.
+func (p *Pool) Get(jti string) (Session, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.sessions[jti]
+	if !ok {
+		p.misses++
+		return Session{}, false
+	}
+	if p.maxIdle > 0 && time.Since(s.LastAccess) > p.maxIdle {
+		delete(p.sessions, jti)
+		p.evictions++
+		p.misses++
+		return Session{}, false
+	}
+
+	p.hits++
+	return *s, true
+}
+
+// Delete removes a session, e.g. on logout or explicit revocation.
+func (p *Pool) Delete(jti string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, jti)
+}
+
+// EvictIdle sweeps the pool for sessions past maxIdle. Call this periodically
+// from a background goroutine; Get also evicts lazily on lookup.
+func (p *Pool) EvictIdle() int {
+	if p.maxIdle <= 0 {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	evicted := 0
+	now := time.Now()
+	for jti, s := range p.sessions {
+		if now.Sub(s.LastAccess) > p.maxIdle {
+			delete(p.sessions, jti)
+			evicted++
+		}
+	}
+	p.evictions += int64(evicted)
+	return evicted
+}
+
+// Metrics returns a snapshot of pool size and access counters.
+func (p *Pool) Metrics() Metrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Metrics{
+		Live:      len(p.sessions),
+		Evictions: p.evictions,
+		Hits:      p.hits,
+		Misses:    p.misses,
+	}
+}
+
+// evictOldestLocked removes the session with the oldest LastAccess. Callers
+// must hold p.mu.
+func (p *Pool) evictOldestLocked() {
+	var oldestJTI string
+	var oldestAccess time.Time
+
+	for jti, s := range p.sessions {
+		if oldestJTI == "" || s.LastAccess.Before(oldestAccess) {
+			oldestJTI = jti
+			oldestAccess = s.LastAccess
+		}
+	}
+
+	if oldestJTI != "" {
+		delete(p.sessions, oldestJTI)
+		p.evictions++
+	}
+}
@@ -0,0 +1,152 @@
+package sessions
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BinWriter writes length-prefixed fields to an underlying writer: each
+// string is written as a uint32 byte-length followed by its bytes, and each
+// int64 as 8 raw bytes, so the snapshot can be read back without a schema.
+type BinWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewBinWriter(w io.Writer) *BinWriter {
+	return &BinWriter{w: bufio.NewWriter(w)}
+}
+
+func (bw *BinWriter) WriteString(s string) {
+	if bw.err != nil {
+		return
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(s)))
+	if _, bw.err = bw.w.Write(lenBuf[:]); bw.err != nil {
+		return
+	}
+	_, bw.err = bw.w.Write([]byte(s))
+}
+
+func (bw *BinWriter) WriteInt64(v int64) {
+	if bw.err != nil {
+		return
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	_, bw.err = bw.w.Write(buf[:])
+}
+
+// Flush flushes the underlying buffer and returns the first write error encountered.
+func (bw *BinWriter) Flush() error {
+	if bw.err != nil {
+		return bw.err
+	}
+	return bw.w.Flush()
+}
+
+// binReader is the read-side counterpart to BinWriter.
+type binReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func newBinReader(r io.Reader) *binReader {
+	return &binReader{r: bufio.NewReader(r)}
+}
+
+func (br *binReader) ReadString() string {
+	if br.err != nil {
+		return ""
+	}
+	var lenBuf [4]byte
+	if _, br.err = io.ReadFull(br.r, lenBuf[:]); br.err != nil {
+		return ""
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	buf := make([]byte, n)
+	if _, br.err = io.ReadFull(br.r, buf); br.err != nil {
+		return ""
+	}
+	return string(buf)
+}
+
+func (br *binReader) ReadInt64() int64 {
+	if br.err != nil {
+		return 0
+	}
+	var buf [8]byte
+	if _, br.err = io.ReadFull(br.r, buf[:]); br.err != nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(buf[:]))
+}
+
+// snapshotVersion guards the on-disk format so a later format change fails
+// loudly on Deserialize instead of silently misreading fields.
+const snapshotVersion = 1
+
+// Serialize writes every live session to w as a durable snapshot.
+func (p *Pool) Serialize(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bw := NewBinWriter(w)
+	bw.WriteInt64(snapshotVersion)
+	bw.WriteInt64(int64(len(p.sessions)))
+
+	for _, s := range p.sessions {
+		bw.WriteString(s.JTI)
+		bw.WriteInt64(s.UserID)
+		bw.WriteString(s.Email)
+		bw.WriteInt64(s.IssuedAt.Unix())
+		bw.WriteInt64(s.LastAccess.Unix())
+	}
+
+	return bw.Flush()
+}
+
+// Deserialize loads a snapshot written by Serialize, replacing the pool's
+// current contents. Sessions already past maxIdle at load time are dropped.
+func (p *Pool) Deserialize(r io.Reader) error {
+	br := newBinReader(r)
+
+	version := br.ReadInt64()
+	if br.err != nil {
+		return br.err
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("sessions: unsupported snapshot version %d", version)
+	}
+
+	count := br.ReadInt64()
+	sessions := make(map[string]*Session, count)
+
+	now := time.Now()
+	for i := int64(0); i < count; i++ {
+		s := &Session{
+			JTI:        br.ReadString(),
+			UserID:     br.ReadInt64(),
+			Email:      br.ReadString(),
+			IssuedAt:   time.Unix(br.ReadInt64(), 0),
+			LastAccess: time.Unix(br.ReadInt64(), 0),
+		}
+		if br.err != nil {
+			return br.err
+		}
+		if p.maxIdle > 0 && now.Sub(s.LastAccess) > p.maxIdle {
+			continue
+		}
+		sessions[s.JTI] = s
+	}
+
+	p.mu.Lock()
+	p.sessions = sessions
+	p.mu.Unlock()
+
+	return nil
+}
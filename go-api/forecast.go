@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ForecastPoint is one projected future period, with 80% and 95%
+// prediction intervals that widen as sqrt(k) over the forecast horizon.
+type ForecastPoint struct {
+	Date    string  `json:"date"`
+	Point   float64 `json:"point"`
+	Lower80 float64 `json:"lower_80"`
+	Upper80 float64 `json:"upper_80"`
+	Lower95 float64 `json:"lower_95"`
+	Upper95 float64 `json:"upper_95"`
+}
+
+// ForecastResponse is the result of fitting a seasonal Holt-Winters model
+// to the historical fiyat_endeksi series for one location/type filter and
+// projecting it forward by horizon periods.
+type ForecastResponse struct {
+	Location string          `json:"location"`
+	Type     string          `json:"type"`
+	Period   int             `json:"period"`
+	Horizon  int             `json:"horizon"`
+	Alpha    float64         `json:"alpha"`
+	Beta     float64         `json:"beta"`
+	Gamma    float64         `json:"gamma"`
+	Forecast []ForecastPoint `json:"forecast"`
+}
+
+// holtWintersModel holds the fitted level, trend and seasonal components
+// of an additive Holt-Winters model as of the last observed period, plus
+// the in-sample residual standard deviation used to scale forecast
+// intervals.
+type holtWintersModel struct {
+	alpha, beta, gamma float64
+	period             int
+	level              float64
+	trend              float64
+	seasonal           []float64 // last `period` seasonal indices, oldest first
+	residualStdDev     float64
+}
+
+// handleGetHousingForecast projects the next N months of fiyat_endeksi for
+// a location/type filter using triple exponential smoothing (Holt-Winters
+// additive), fit in-process over the full filtered time series. Fitting
+// holds out the final season to score each candidate (alpha, beta, gamma)
+// by forecast error, so it needs two full seasons of training data on top
+// of that held-out one — three seasons of history in total — and returns
+// 422 otherwise.
+func handleGetHousingForecast(w http.ResponseWriter, r *http.Request, dbPool *pgxpool.Pool) {
+	ctx := context.Background()
+
+	location := r.URL.Query().Get("location")
+	konutType := r.URL.Query().Get("type")
+	if location == "" || konutType == "" {
+		http.Error(w, "location and type parameters are required for forecast", http.StatusBadRequest)
+		return
+	}
+
+	horizon := 12
+	if h := r.URL.Query().Get("horizon"); h != "" {
+		parsed, err := strconv.Atoi(h)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "horizon must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		horizon = parsed
+	}
+
+	period := 12
+	if p := r.URL.Query().Get("period"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil || parsed <= 1 {
+			http.Error(w, "period must be an integer greater than 1", http.StatusBadRequest)
+			return
+		}
+		period = parsed
+	}
+
+	rows, err := dbPool.Query(ctx, `
+		SELECT tarih, fiyat_endeksi
+		FROM housing_price_index
+		WHERE istanbul_turkiye = $1 AND yeni_yeni_olmayan_konut = $2
+		ORDER BY tarih ASC`, location, konutType)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Database error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	var values []float64
+	for rows.Next() {
+		var tarih time.Time
+		var value float64
+		if err := rows.Scan(&tarih, &value); err != nil {
+			http.Error(w, fmt.Sprintf("Scan error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		dates = append(dates, tarih)
+		values = append(values, value)
+	}
+
+	if len(values) < 3*period {
+		http.Error(w, fmt.Sprintf("at least %d data points (three full seasons) are required for a forecast", 3*period), http.StatusUnprocessableEntity)
+		return
+	}
+
+	model := fitHoltWinters(values, period)
+
+	lastDate := dates[len(dates)-1]
+	forecast := make([]ForecastPoint, 0, horizon)
+	for k := 1; k <= horizon; k++ {
+		point, stdErr := model.forecast(k)
+		forecast = append(forecast, ForecastPoint{
+			Date:    lastDate.AddDate(0, k, 0).Format("2006-01-02"),
+			Point:   point,
+			Lower80: point - 1.2816*stdErr,
+			Upper80: point + 1.2816*stdErr,
+			Lower95: point - 1.96*stdErr,
+			Upper95: point + 1.96*stdErr,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ForecastResponse{
+		Location: location,
+		Type:     konutType,
+		Period:   period,
+		Horizon:  horizon,
+		Alpha:    model.alpha,
+		Beta:     model.beta,
+		Gamma:    model.gamma,
+		Forecast: forecast,
+	})
+}
+
+// fitHoltWinters fits additive triple exponential smoothing to series,
+// picking alpha/beta/gamma by a grid search over (0,1) that minimizes SSE
+// on the final season, held out as a validation tail.
+func fitHoltWinters(series []float64, period int) *holtWintersModel {
+	holdoutStart := len(series) - period
+
+	// Copied rather than resliced as a defense-in-depth measure: the
+	// 3*period floor above guarantees train is long enough for
+	// runHoltWinters's 2*period indexing on its own, but capping train's
+	// capacity here too means a future caller with a shorter train panics
+	// loudly instead of silently reading into holdout's backing array.
+	train := append(make([]float64, 0, holdoutStart), series[:holdoutStart]...)
+	holdout := series[holdoutStart:]
+
+	type candidate struct {
+		alpha, beta, gamma float64
+	}
+	best := candidate{alpha: 0.3, beta: 0.1, gamma: 0.1}
+	bestSSE := math.Inf(1)
+
+	grid := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	for _, alpha := range grid {
+		for _, beta := range grid {
+			for _, gamma := range grid {
+				sse := holtWintersHoldoutSSE(train, holdout, period, alpha, beta, gamma)
+				if sse < bestSSE {
+					bestSSE = sse
+					best = candidate{alpha, beta, gamma}
+				}
+			}
+		}
+	}
+
+	return runHoltWinters(series, period, best.alpha, best.beta, best.gamma)
+}
+
+// holtWintersHoldoutSSE fits the model on train and sums squared errors of
+// its forecasts against holdout, which is assumed to immediately follow it.
+func holtWintersHoldoutSSE(train, holdout []float64, period int, alpha, beta, gamma float64) float64 {
+	model := runHoltWinters(train, period, alpha, beta, gamma)
+	var sse float64
+	for k, actual := range holdout {
+		point, _ := model.forecast(k + 1)
+		diff := actual - point
+		sse += diff * diff
+	}
+	return sse
+}
+
+// runHoltWinters runs the standard additive Holt-Winters recurrences over
+// series: level L0 is the mean of the first season, trend b0 is the
+// average per-step change between the first two seasons, and seasonal
+// indices S_i start as value_i - L0 over the first season.
+func runHoltWinters(series []float64, period int, alpha, beta, gamma float64) *holtWintersModel {
+	n := len(series)
+	seasonal := make([]float64, n)
+
+	firstSeasonMean := mean(series[:period])
+	secondSeasonMean := mean(series[period : 2*period])
+
+	level := firstSeasonMean
+	trend := (secondSeasonMean - firstSeasonMean) / float64(period)
+
+	for i := 0; i < period; i++ {
+		seasonal[i] = series[i] - firstSeasonMean
+	}
+
+	var sumSquaredResiduals float64
+	var residualCount int
+
+	for t := period; t < n; t++ {
+		prevLevel, prevTrend := level, trend
+		prevSeasonal := seasonal[t-period]
+
+		residual := series[t] - (prevLevel + prevTrend + prevSeasonal)
+		sumSquaredResiduals += residual * residual
+		residualCount++
+
+		level = alpha*(series[t]-prevSeasonal) + (1-alpha)*(prevLevel+prevTrend)
+		trend = beta*(level-prevLevel) + (1-beta)*prevTrend
+		seasonal[t] = gamma*(series[t]-level) + (1-gamma)*prevSeasonal
+	}
+
+	residualStdDev := 0.0
+	if residualCount > 0 {
+		residualStdDev = math.Sqrt(sumSquaredResiduals / float64(residualCount))
+	}
+
+	return &holtWintersModel{
+		alpha:          alpha,
+		beta:           beta,
+		gamma:          gamma,
+		period:         period,
+		level:          level,
+		trend:          trend,
+		seasonal:       append([]float64(nil), seasonal[n-period:]...),
+		residualStdDev: residualStdDev,
+	}
+}
+
+// forecast projects k periods past the last observed point. stdErr is the
+// residual standard deviation scaled by sqrt(k); a prediction interval's
+// half-width is stdErr times the interval's z-score.
+func (m *holtWintersModel) forecast(k int) (point float64, stdErr float64) {
+	idx := (k - 1) % m.period
+	point = m.level + float64(k)*m.trend + m.seasonal[idx]
+	stdErr = m.residualStdDev * math.Sqrt(float64(k))
+	return point, stdErr
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
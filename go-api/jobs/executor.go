@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Executor runs the actual sync work a policy describes. Source/target refs
+// are looked up by name from pre-registered Postgres pools and Redis clients,
+// so a policy row only needs to name a ref, not a connection string.
+type Executor struct {
+	pgRefs    map[string]*pgxpool.Pool
+	redisRefs map[string]*redis.Client
+}
+
+func NewExecutor() *Executor {
+	return &Executor{
+		pgRefs:    make(map[string]*pgxpool.Pool),
+		redisRefs: make(map[string]*redis.Client),
+	}
+}
+
+// RegisterPostgresRef makes a pool addressable by name in policies' source_ref/target_ref.
+func (e *Executor) RegisterPostgresRef(name string, pool *pgxpool.Pool) {
+	e.pgRefs[name] = pool
+}
+
+// RegisterRedisRef makes a Redis client addressable by name in policies' source_ref/target_ref.
+func (e *Executor) RegisterRedisRef(name string, client *redis.Client) {
+	e.redisRefs[name] = client
+}
+
+// Run executes a policy and returns per-row stats as JSON. The
+// resource_selector decides what's being synced: "table:<name>" for a
+// Postgres table copy, or "cache:<pattern>" for a Redis key-glob copy.
+func (e *Executor) Run(ctx context.Context, policy ReplicationPolicy) (json.RawMessage, error) {
+	switch {
+	case strings.HasPrefix(policy.ResourceSelector, "table:"):
+		table := strings.TrimPrefix(policy.ResourceSelector, "table:")
+		return e.syncTable(ctx, policy, table)
+	case strings.HasPrefix(policy.ResourceSelector, "cache:"):
+		pattern := strings.TrimPrefix(policy.ResourceSelector, "cache:")
+		return e.syncCache(ctx, policy, pattern)
+	default:
+		return nil, fmt.Errorf("unsupported resource_selector %q", policy.ResourceSelector)
+	}
+}
+
+// syncTable copies every row of a table from the source Postgres ref to the
+// target ref, upserting on primary key id.
+func (e *Executor) syncTable(ctx context.Context, policy ReplicationPolicy, table string) (json.RawMessage, error) {
+	src, ok := e.pgRefs[policy.SourceRef]
+	if !ok {
+		return nil, fmt.Errorf("unknown postgres source_ref %q", policy.SourceRef)
+	}
+	dst, ok := e.pgRefs[policy.TargetRef]
+	if !ok {
+		return nil, fmt.Errorf("unknown postgres target_ref %q", policy.TargetRef)
+	}
+
+	// table comes from the policy's resource_selector, which is free text
+	// settable via the policy CRUD API — sanitize it as an identifier
+	// (quoting it and rejecting anything that isn't a plain name, optionally
+	// schema-qualified) before it ever reaches a query string.
+	quotedTable, err := sanitizeTableName(table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := src.Query(ctx, fmt.Sprintf("SELECT * FROM %s", quotedTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	columns := make([]string, len(fields))
+	quotedColumns := make([]string, len(fields))
+	placeholders := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = string(f.Name)
+		quotedColumns[i] = pgx.Identifier{string(f.Name)}.Sanitize()
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	insert := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) DO UPDATE SET %s",
+		quotedTable, strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", "), conflictAssignments(columns),
+	)
+
+	copied := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := dst.Exec(ctx, insert, values...); err != nil {
+			return nil, err
+		}
+		copied++
+	}
+
+	return json.Marshal(map[string]interface{}{"table": table, "rows_copied": copied})
+}
+
+func conflictAssignments(columns []string) string {
+	assignments := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c == "id" {
+			continue
+		}
+		quoted := pgx.Identifier{c}.Sanitize()
+		assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted))
+	}
+	return strings.Join(assignments, ", ")
+}
+
+// sanitizeTableName validates that table is a plain identifier, optionally
+// schema-qualified (e.g. "public.users"), and returns it quoted for safe
+// interpolation into a query string. It rejects anything else, since table
+// comes from a policy's resource_selector, which is attacker-controllable
+// free text rather than a fixed, trusted value.
+func sanitizeTableName(table string) (string, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", fmt.Errorf("invalid table name %q", table)
+	}
+	for _, p := range parts {
+		if p == "" || !isPlainIdentifier(p) {
+			return "", fmt.Errorf("invalid table name %q", table)
+		}
+	}
+	return pgx.Identifier(parts).Sanitize(), nil
+}
+
+func isPlainIdentifier(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// syncCache copies every key matching pattern from the source Redis ref to
+// the target ref, preserving remaining TTL.
+func (e *Executor) syncCache(ctx context.Context, policy ReplicationPolicy, pattern string) (json.RawMessage, error) {
+	src, ok := e.redisRefs[policy.SourceRef]
+	if !ok {
+		return nil, fmt.Errorf("unknown redis source_ref %q", policy.SourceRef)
+	}
+	dst, ok := e.redisRefs[policy.TargetRef]
+	if !ok {
+		return nil, fmt.Errorf("unknown redis target_ref %q", policy.TargetRef)
+	}
+
+	copied := 0
+	iter := src.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		value, err := src.Get(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		ttl, err := src.TTL(ctx, key).Result()
+		if err != nil {
+			ttl = 0
+		}
+		if ttl < 0 {
+			// TTL returns a negative duration for keys with no expiry (or that
+			// don't exist); Redis rejects a negative PX on Set, so treat it as
+			// "no expiry" rather than aborting the whole job.
+			ttl = 0
+		}
+
+		if err := dst.Set(ctx, key, value, ttl).Err(); err != nil {
+			return nil, err
+		}
+		copied++
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{"pattern": pattern, "keys_copied": copied})
+}
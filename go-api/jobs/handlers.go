@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler exposes replication policies and jobs over HTTP.
+type Handler struct {
+	policies  *PolicyStore
+	jobs      *JobStore
+	scheduler *Scheduler
+}
+
+func NewHandler(policies *PolicyStore, jobStore *JobStore, scheduler *Scheduler) *Handler {
+	return &Handler{policies: policies, jobs: jobStore, scheduler: scheduler}
+}
+
+// CreatePolicy creates a new replication policy.
+func (h *Handler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var p ReplicationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if p.Name == "" || p.SourceRef == "" || p.TargetRef == "" || p.ResourceSelector == "" {
+		h.sendError(w, http.StatusBadRequest, "name, source_ref, target_ref, and resource_selector are required")
+		return
+	}
+	if p.Trigger == "" {
+		p.Trigger = TriggerManual
+	}
+
+	created, err := h.policies.Create(r.Context(), &p)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to create policy")
+		return
+	}
+	h.reloadScheduler(r.Context())
+
+	h.sendJSON(w, http.StatusCreated, created)
+}
+
+// ListPolicies returns all replication policies.
+func (h *Handler) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.policies.List(r.Context())
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to list policies")
+		return
+	}
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{"policies": policies})
+}
+
+// UpdatePolicy updates an existing policy.
+func (h *Handler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid policy id")
+		return
+	}
+
+	var p ReplicationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	p.ID = id
+
+	if err := h.policies.Update(r.Context(), &p); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to update policy")
+		return
+	}
+	h.reloadScheduler(r.Context())
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "policy updated"})
+}
+
+// DeletePolicy removes a policy.
+func (h *Handler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid policy id")
+		return
+	}
+
+	if err := h.policies.Delete(r.Context(), id); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to delete policy")
+		return
+	}
+	h.reloadScheduler(r.Context())
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "policy deleted"})
+}
+
+// TriggerPolicy manually enqueues a job for a policy.
+func (h *Handler) TriggerPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid policy id")
+		return
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to enqueue job")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, job)
+}
+
+// ListJobs returns jobs, optionally filtered by policy_id.
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	var policyID int64
+	if v := r.URL.Query().Get("policy_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "invalid policy_id")
+			return
+		}
+		policyID = id
+	}
+
+	jobList, err := h.jobs.List(r.Context(), policyID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{"jobs": jobList})
+}
+
+// RetryJob re-queues a failed or cancelled job.
+func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid job id")
+		return
+	}
+
+	if err := h.jobs.Retry(r.Context(), id); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to retry job")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "job re-queued"})
+}
+
+// CancelJob cancels a pending or running job.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "invalid job id")
+		return
+	}
+
+	if err := h.jobs.Cancel(r.Context(), id); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "failed to cancel job")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "job cancelled"})
+}
+
+// reloadScheduler brings the running scheduler's cron entries in line with
+// the policy store immediately, so a create/update/delete through this API
+// takes effect without waiting for a process restart.
+func (h *Handler) reloadScheduler(ctx context.Context) {
+	if h.scheduler == nil {
+		return
+	}
+	if err := h.scheduler.Reload(ctx); err != nil {
+		log.Printf("replication: failed to reload scheduler: %v", err)
+	}
+}
+
+func (h *Handler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendJSON(w, status, map[string]string{"error": message})
+}
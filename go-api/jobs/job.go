@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status is a replication job's lifecycle state.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// ReplicationJob is a single run of a ReplicationPolicy.
+type ReplicationJob struct {
+	ID         int64           `json:"id"`
+	PolicyID   int64           `json:"policy_id"`
+	Status     Status          `json:"status"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	StatsJSON  json.RawMessage `json:"stats_json,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// JobStore persists replication jobs and supports claiming pending work
+// across a pool of workers using SELECT ... FOR UPDATE SKIP LOCKED.
+type JobStore struct {
+	db *pgxpool.Pool
+}
+
+func NewJobStore(db *pgxpool.Pool) *JobStore {
+	return &JobStore{db: db}
+}
+
+// Enqueue creates a pending job for a policy.
+func (s *JobStore) Enqueue(ctx context.Context, policyID int64) (*ReplicationJob, error) {
+	query := `
+		INSERT INTO replication_job (policy_id, status, created_at)
+		VALUES ($1, $2, now())
+		RETURNING id, created_at
+	`
+
+	job := &ReplicationJob{PolicyID: policyID, Status: StatusPending}
+	err := s.db.QueryRow(ctx, query, policyID, StatusPending).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimNext atomically claims one pending job for a worker, transitioning it
+// to running. Returns (nil, nil) if there is nothing to claim.
+func (s *JobStore) ClaimNext(ctx context.Context) (*ReplicationJob, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var job ReplicationJob
+	query := `
+		SELECT id, policy_id, status, started_at, finished_at, stats_json, error, created_at
+		FROM replication_job
+		WHERE status = $1
+		ORDER BY created_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`
+
+	err = tx.QueryRow(ctx, query, StatusPending).
+		Scan(&job.ID, &job.PolicyID, &job.Status, &job.StartedAt, &job.FinishedAt, &job.StatsJSON, &job.Error, &job.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(ctx, "UPDATE replication_job SET status = $1, started_at = $2 WHERE id = $3", StatusRunning, now, job.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.Status = StatusRunning
+	job.StartedAt = &now
+	return &job, nil
+}
+
+// Finish records a job's terminal state and per-row stats.
+func (s *JobStore) Finish(ctx context.Context, jobID int64, status Status, stats json.RawMessage, jobErr string) error {
+	query := `
+		UPDATE replication_job
+		SET status = $1, finished_at = now(), stats_json = $2, error = $3
+		WHERE id = $4
+	`
+	_, err := s.db.Exec(ctx, query, status, stats, jobErr, jobID)
+	return err
+}
+
+// ResetRunning reverts jobs stuck in "running" back to "pending" so they're
+// resumable after a restart.
+func (s *JobStore) ResetRunning(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, "UPDATE replication_job SET status = $1 WHERE status = $2", StatusPending, StatusRunning)
+	return err
+}
+
+// List returns jobs, most recent first, optionally filtered by policy.
+func (s *JobStore) List(ctx context.Context, policyID int64) ([]ReplicationJob, error) {
+	query := `
+		SELECT id, policy_id, status, started_at, finished_at, stats_json, error, created_at
+		FROM replication_job
+		WHERE ($1 = 0 OR policy_id = $1)
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, policyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []ReplicationJob
+	for rows.Next() {
+		var j ReplicationJob
+		if err := rows.Scan(&j.ID, &j.PolicyID, &j.Status, &j.StartedAt, &j.FinishedAt, &j.StatsJSON, &j.Error, &j.CreatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// Retry re-queues a failed or cancelled job as pending.
+func (s *JobStore) Retry(ctx context.Context, jobID int64) error {
+	query := `
+		UPDATE replication_job
+		SET status = $1, started_at = NULL, finished_at = NULL, error = ''
+		WHERE id = $2 AND status IN ($3, $4)
+	`
+	_, err := s.db.Exec(ctx, query, StatusPending, jobID, StatusFailed, StatusCancelled)
+	return err
+}
+
+// Cancel marks a pending or running job cancelled.
+func (s *JobStore) Cancel(ctx context.Context, jobID int64) error {
+	query := `
+		UPDATE replication_job
+		SET status = $1, finished_at = now()
+		WHERE id = $2 AND status IN ($3, $4)
+	`
+	_, err := s.db.Exec(ctx, query, StatusCancelled, jobID, StatusPending, StatusRunning)
+	return err
+}
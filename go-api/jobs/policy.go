@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TriggerType describes how a policy's jobs get created.
+type TriggerType string
+
+const (
+	TriggerManual    TriggerType = "manual"
+	TriggerScheduled TriggerType = "scheduled"
+	TriggerEvent     TriggerType = "event"
+)
+
+// ReplicationPolicy declares what to sync, from where to where, and on what
+// schedule, mirroring Harbor's replication-policy model.
+type ReplicationPolicy struct {
+	ID               int64       `json:"id"`
+	Name             string      `json:"name"`
+	SourceRef        string      `json:"source_ref"`
+	TargetRef        string      `json:"target_ref"`
+	ResourceSelector string      `json:"resource_selector"`
+	CronStr          string      `json:"cron_str"`
+	Enabled          bool        `json:"enabled"`
+	Trigger          TriggerType `json:"trigger"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// PolicyStore persists replication policies in Postgres.
+type PolicyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPolicyStore(db *pgxpool.Pool) *PolicyStore {
+	return &PolicyStore{db: db}
+}
+
+// Create inserts a new replication policy.
+func (s *PolicyStore) Create(ctx context.Context, p *ReplicationPolicy) (*ReplicationPolicy, error) {
+	query := `
+		INSERT INTO replication_policy (name, source_ref, target_ref, resource_selector, cron_str, enabled, trigger, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+		RETURNING id, created_at, updated_at
+	`
+
+	var created ReplicationPolicy = *p
+	err := s.db.QueryRow(ctx, query, p.Name, p.SourceRef, p.TargetRef, p.ResourceSelector, p.CronStr, p.Enabled, p.Trigger).
+		Scan(&created.ID, &created.CreatedAt, &created.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// List returns all replication policies.
+func (s *PolicyStore) List(ctx context.Context) ([]ReplicationPolicy, error) {
+	query := `
+		SELECT id, name, source_ref, target_ref, resource_selector, cron_str, enabled, trigger, created_at, updated_at
+		FROM replication_policy
+		ORDER BY id
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []ReplicationPolicy
+	for rows.Next() {
+		var p ReplicationPolicy
+		if err := rows.Scan(&p.ID, &p.Name, &p.SourceRef, &p.TargetRef, &p.ResourceSelector, &p.CronStr, &p.Enabled, &p.Trigger, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// ListEnabled returns all enabled, cron-triggered policies; used by the
+// scheduler to know what to register with robfig/cron.
+func (s *PolicyStore) ListEnabled(ctx context.Context) ([]ReplicationPolicy, error) {
+	query := `
+		SELECT id, name, source_ref, target_ref, resource_selector, cron_str, enabled, trigger, created_at, updated_at
+		FROM replication_policy
+		WHERE enabled = true AND trigger = 'scheduled'
+		ORDER BY id
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []ReplicationPolicy
+	for rows.Next() {
+		var p ReplicationPolicy
+		if err := rows.Scan(&p.ID, &p.Name, &p.SourceRef, &p.TargetRef, &p.ResourceSelector, &p.CronStr, &p.Enabled, &p.Trigger, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// Update modifies an existing policy.
+func (s *PolicyStore) Update(ctx context.Context, p *ReplicationPolicy) error {
+	query := `
+		UPDATE replication_policy
+		SET name = $1, source_ref = $2, target_ref = $3, resource_selector = $4,
+		    cron_str = $5, enabled = $6, trigger = $7, updated_at = now()
+		WHERE id = $8
+	`
+
+	_, err := s.db.Exec(ctx, query, p.Name, p.SourceRef, p.TargetRef, p.ResourceSelector, p.CronStr, p.Enabled, p.Trigger, p.ID)
+	return err
+}
+
+// Delete removes a policy.
+func (s *PolicyStore) Delete(ctx context.Context, id int64) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM replication_policy WHERE id = $1", id)
+	return err
+}
+
+// Get retrieves a single policy by id.
+func (s *PolicyStore) Get(ctx context.Context, id int64) (*ReplicationPolicy, error) {
+	query := `
+		SELECT id, name, source_ref, target_ref, resource_selector, cron_str, enabled, trigger, created_at, updated_at
+		FROM replication_policy
+		WHERE id = $1
+	`
+
+	var p ReplicationPolicy
+	err := s.db.QueryRow(ctx, query, id).
+		Scan(&p.ID, &p.Name, &p.SourceRef, &p.TargetRef, &p.ResourceSelector, &p.CronStr, &p.Enabled, &p.Trigger, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
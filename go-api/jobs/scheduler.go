@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler enqueues jobs for scheduled policies on their cron schedule and
+// runs a worker pool that claims and executes pending jobs. Jobs are
+// resumable across restarts: anything left "running" is reset to "pending"
+// on Start, and ClaimNext uses SELECT ... FOR UPDATE SKIP LOCKED so workers
+// never double-process a row.
+type Scheduler struct {
+	policies  *PolicyStore
+	jobs      *JobStore
+	executor  *Executor
+	cron      *cron.Cron
+	workers   int
+	pollEvery time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]cron.EntryID
+
+	stop chan struct{}
+}
+
+// NewScheduler builds a scheduler with the given worker-pool size.
+func NewScheduler(policies *PolicyStore, jobStore *JobStore, executor *Executor, workers int) *Scheduler {
+	return &Scheduler{
+		policies:  policies,
+		jobs:      jobStore,
+		executor:  executor,
+		cron:      cron.New(),
+		workers:   workers,
+		pollEvery: 2 * time.Second,
+		entries:   make(map[int64]cron.EntryID),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start loads enabled scheduled policies into the cron runner, resumes any
+// jobs left running from a prior process, and launches the worker pool.
+func (s *Scheduler) Start(ctx context.Context) error {
+	if err := s.jobs.ResetRunning(ctx); err != nil {
+		return err
+	}
+
+	if err := s.Reload(ctx); err != nil {
+		return err
+	}
+
+	s.cron.Start()
+
+	for i := 0; i < s.workers; i++ {
+		go s.workerLoop()
+	}
+
+	return nil
+}
+
+// Reload re-reads enabled scheduled policies and brings the cron runner's
+// registered entries in line with them, so a policy created, edited, or
+// disabled through the API takes effect immediately instead of only on the
+// next process restart.
+func (s *Scheduler) Reload(ctx context.Context) error {
+	policies, err := s.policies.ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int64]bool, len(policies))
+	for _, policy := range policies {
+		policy := policy
+		seen[policy.ID] = true
+
+		if entryID, ok := s.entries[policy.ID]; ok {
+			s.cron.Remove(entryID)
+			delete(s.entries, policy.ID)
+		}
+
+		entryID, err := s.cron.AddFunc(policy.CronStr, func() {
+			if _, err := s.jobs.Enqueue(context.Background(), policy.ID); err != nil {
+				log.Printf("replication: failed to enqueue job for policy %d: %v", policy.ID, err)
+			}
+		})
+		if err != nil {
+			log.Printf("replication: skipping policy %d, bad cron string %q: %v", policy.ID, policy.CronStr, err)
+			continue
+		}
+		s.entries[policy.ID] = entryID
+	}
+
+	for id, entryID := range s.entries {
+		if !seen[id] {
+			s.cron.Remove(entryID)
+			delete(s.entries, id)
+		}
+	}
+
+	return nil
+}
+
+// Stop halts the cron runner and worker pool.
+func (s *Scheduler) Stop() {
+	s.cron.Stop()
+	close(s.stop)
+}
+
+func (s *Scheduler) workerLoop() {
+	ticker := time.NewTicker(s.pollEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.runOne()
+		}
+	}
+}
+
+func (s *Scheduler) runOne() {
+	ctx := context.Background()
+
+	job, err := s.jobs.ClaimNext(ctx)
+	if err != nil {
+		log.Printf("replication: failed to claim job: %v", err)
+		return
+	}
+	if job == nil {
+		return
+	}
+
+	policy, err := s.policies.Get(ctx, job.PolicyID)
+	if err != nil {
+		s.jobs.Finish(ctx, job.ID, StatusFailed, nil, "policy not found: "+err.Error())
+		return
+	}
+
+	stats, err := s.executor.Run(ctx, *policy)
+	if err != nil {
+		s.jobs.Finish(ctx, job.ID, StatusFailed, stats, err.Error())
+		return
+	}
+
+	s.jobs.Finish(ctx, job.ID, StatusSucceeded, stats, "")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// RBAC enforces permission checks on the housing API. It validates the
+// caller's bearer token against auth-service's published JWKS and checks
+// permissions against the same roles/permissions tables auth-service owns,
+// since both services share the same Postgres database. It also checks the
+// token against the same revoked-jti blacklist and token_version counter the
+// gateway's JWTMiddleware does, so a token revoked via
+// Logout/RevokeSession/RevokeUserTokens is rejected here too rather than
+// staying valid against housing:read-gated routes until it expires.
+type RBAC struct {
+	db      *pgxpool.Pool
+	jwks    *JWKSClient
+	redis   *redis.Client
+	revoked *revocationCache
+}
+
+func NewRBAC(db *pgxpool.Pool, jwksURL string, redisClient *redis.Client) *RBAC {
+	return &RBAC{
+		db:      db,
+		jwks:    NewJWKSClient(jwksURL),
+		redis:   redisClient,
+		revoked: newRevocationCache(10000, 30*time.Second),
+	}
+}
+
+// RequirePermission 403s unless the caller's roles (including inherited
+// parent roles) grant perm.
+func (rb *RBAC) RequirePermission(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := rb.userIDFromRequest(r)
+			if !ok {
+				rb.forbidden(w, http.StatusUnauthorized, "missing or invalid authorization header")
+				return
+			}
+
+			allowed, err := rb.hasPermission(r.Context(), userID, perm)
+			if err != nil {
+				rb.forbidden(w, http.StatusInternalServerError, "failed to check permissions")
+				return
+			}
+			if !allowed {
+				rb.forbidden(w, http.StatusForbidden, "insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rb *RBAC) hasPermission(ctx context.Context, userID int64, perm string) (bool, error) {
+	query := `
+		WITH RECURSIVE role_tree AS (
+			SELECT r.id, r.parent_role_id
+			FROM roles r
+			INNER JOIN user_roles ur ON ur.role_id = r.id
+			WHERE ur.user_id = $1
+
+			UNION
+
+			SELECT parent.id, parent.parent_role_id
+			FROM roles parent
+			INNER JOIN role_tree rt ON parent.id = rt.parent_role_id
+		)
+		SELECT EXISTS(
+			SELECT 1 FROM permissions p
+			INNER JOIN role_permissions rp ON rp.permission_id = p.id
+			WHERE rp.role_id IN (SELECT id FROM role_tree) AND p.name = $2
+		)
+	`
+
+	var allowed bool
+	err := rb.db.QueryRow(ctx, query, userID, perm).Scan(&allowed)
+	return allowed, err
+}
+
+func (rb *RBAC) userIDFromRequest(r *http.Request) (int64, bool) {
+	authHeader := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return 0, false
+	}
+
+	token, err := jwt.Parse(authHeader[len(prefix):], func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, jwt.ErrTokenMalformed
+		}
+		return rb.jwks.Key(kid)
+	})
+	if err != nil || !token.Valid {
+		return 0, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	userID := int64(userIDFloat)
+
+	if rb.redis != nil {
+		userIDStr := strconv.FormatInt(userID, 10)
+
+		if jti, _ := claims["jti"].(string); jti != "" {
+			revokedJTI, err := rb.isJTIRevoked(r.Context(), jti)
+			if err != nil || revokedJTI {
+				return 0, false
+			}
+		}
+
+		if tv, ok := claims["tv"].(float64); ok {
+			current, err := rb.isTokenVersionCurrent(r.Context(), userIDStr, int64(tv))
+			if err != nil || !current {
+				return 0, false
+			}
+		}
+	}
+
+	return userID, true
+}
+
+// isJTIRevoked checks the revoked-jti blacklist auth-service writes to on
+// logout or session revocation, via a local LRU in front of Redis.
+func (rb *RBAC) isJTIRevoked(ctx context.Context, jti string) (bool, error) {
+	cacheKey := "jti:" + jti
+	if revoked, ok := rb.revoked.get(cacheKey); ok {
+		return revoked, nil
+	}
+
+	exists, err := rb.redis.Exists(ctx, fmt.Sprintf("revoked:jti:%s", jti)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	revoked := exists == 1
+	rb.revoked.set(cacheKey, revoked)
+	return revoked, nil
+}
+
+// isTokenVersionCurrent checks a token's tv claim against the user's current
+// token_version counter, which auth-service bumps to invalidate every token
+// a user holds at once (e.g. an admin-initiated lockout).
+func (rb *RBAC) isTokenVersionCurrent(ctx context.Context, userID string, tokenVersion int64) (bool, error) {
+	cacheKey := fmt.Sprintf("tv:%s:%d", userID, tokenVersion)
+	if current, ok := rb.revoked.get(cacheKey); ok {
+		return current, nil
+	}
+
+	currentVersion, err := rb.redis.Get(ctx, fmt.Sprintf("user:token_version:%s", userID)).Int64()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	valid := tokenVersion >= currentVersion
+	rb.revoked.set(cacheKey, valid)
+	return valid, nil
+}
+
+func (rb *RBAC) forbidden(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
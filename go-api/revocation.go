@@ -0,0 +1,79 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// revocationCache is a small fixed-size LRU in front of Redis so the hot
+// path (every RBAC-gated request) doesn't need a Redis round trip just to
+// check whether a jti was blacklisted or a token_version claim is still
+// current. Entries still expire after ttl regardless of LRU position, since
+// a revocation needs to become visible quickly.
+type revocationCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type revocationEntry struct {
+	key       string
+	value     bool
+	expiresAt time.Time
+}
+
+func newRevocationCache(capacity int, ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *revocationCache) get(key string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false, false
+	}
+
+	entry := el.Value.(*revocationEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return false, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *revocationCache) set(key string, value bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*revocationEntry)
+		entry.value = value
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&revocationEntry{key: key, value: value, expiresAt: time.Now().Add(c.ttl)})
+	c.items[key] = el
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*revocationEntry).key)
+		}
+	}
+}